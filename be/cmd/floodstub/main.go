@@ -1,24 +1,154 @@
+// Command floodstub is a scenario-driven stand-in for an external flood risk
+// API, for testing clients of such a service without real services. weatherd
+// asks it for flood risk when FLOOD_API_URL points at its /risk endpoint,
+// and falls back to its own precipitation model when it fails.
+//
+// Responses are chosen by matching the request coordinates against the
+// scenarios file; latency and error responses can be injected globally or
+// per scenario, and every call received is listed at /requests.
 package main
 
 import (
 	"encoding/json"
+	"flag"
 	"log"
+	"math/rand"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
+// maxRecorded bounds the request log kept for /requests.
+const maxRecorded = 1000
+
+// statusClientClosedRequest is recorded for a call whose client went away
+// during the injected latency; nothing is written back.
+const statusClientClosedRequest = 499
+
+// recordedRequest is one call as reported by /requests.
+type recordedRequest struct {
+	Time      time.Time `json:"time"`
+	Method    string    `json:"method"`
+	Path      string    `json:"path"`
+	Query     string    `json:"query"`
+	Latitude  *float64  `json:"latitude,omitempty"`
+	Longitude *float64  `json:"longitude,omitempty"`
+	Scenario  string    `json:"scenario,omitempty"`
+	Status    int       `json:"status"`
+}
+
+type stub struct {
+	scenarios   *scenarioSet
+	latency     time.Duration
+	errorRate   float64
+	errorStatus int
+
+	mu       sync.Mutex
+	requests []recordedRequest
+}
+
 func main() {
-	http.HandleFunc("/risk", func(w http.ResponseWriter, r *http.Request) {
-		q := r.URL.Query()
-		lat := q.Get("latitude")
-		lon := q.Get("longitude")
-		payload := map[string]interface{}{
-			"flood_risk":  "high",
-			"probability": 0.82,
-			"coords":      map[string]string{"lat": lat, "lon": lon},
+	port := flag.Int("port", 9000, "port to listen on")
+	file := flag.String("scenarios", "", "JSON or YAML scenarios file (default: always high, 0.82)")
+	latency := flag.Duration("latency", 0, "delay added to every /risk response")
+	errorRate := flag.Float64("error-rate", 0, "fraction (0-1) of /risk calls answered with -error-status")
+	errorStatus := flag.Int("error-status", http.StatusServiceUnavailable, "status code for injected errors")
+	flag.Parse()
+
+	scenarios := defaultScenarios()
+	if *file != "" {
+		var err error
+		if scenarios, err = loadScenarios(*file); err != nil {
+			log.Fatalf("failed to load scenarios: %v", err)
 		}
-		w.Header().Set("Content-Type", "application/json; charset=utf-8")
-		_ = json.NewEncoder(w).Encode(payload)
-	})
-	log.Println("flood stub listening on :9000")
-	log.Fatal(http.ListenAndServe(":9000", nil))
+		log.Printf("loaded %d scenarios from %s", len(scenarios.Scenarios), *file)
+	}
+	s := &stub{scenarios: scenarios, latency: *latency, errorRate: *errorRate, errorStatus: *errorStatus}
+
+	addr := ":" + strconv.Itoa(*port)
+	log.Printf("flood stub listening on %s", addr)
+	log.Fatal(http.ListenAndServe(addr, s.handler()))
+}
+
+func (s *stub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/risk", s.risk)
+	mux.HandleFunc("/requests", s.listRequests)
+	return mux
+}
+
+func (s *stub) risk(w http.ResponseWriter, r *http.Request) {
+	rec := recordedRequest{Time: time.Now(), Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery}
+	defer func() { s.record(rec) }()
+
+	q := r.URL.Query()
+	lat, latErr := strconv.ParseFloat(q.Get("latitude"), 64)
+	lon, lonErr := strconv.ParseFloat(q.Get("longitude"), 64)
+	if latErr != nil || lonErr != nil {
+		rec.Status = http.StatusBadRequest
+		writeJSON(w, rec.Status, map[string]string{"error": "latitude and longitude are required"})
+		return
+	}
+	rec.Latitude, rec.Longitude = &lat, &lon
+
+	sc := s.scenarios.match(lat, lon)
+	rec.Scenario = sc.Name
+	if d := s.latency + time.Duration(sc.LatencyMS)*time.Millisecond; d > 0 {
+		select {
+		case <-time.After(d):
+		case <-r.Context().Done():
+			rec.Status = statusClientClosedRequest
+			return
+		}
+	}
+
+	switch {
+	case s.errorRate > 0 && rand.Float64() < s.errorRate:
+		rec.Status = s.errorStatus
+		writeJSON(w, rec.Status, map[string]string{"error": "injected error"})
+	case sc.Status >= 400:
+		rec.Status = sc.Status
+		writeJSON(w, rec.Status, map[string]string{"error": "scenario " + sc.Name})
+	default:
+		rec.Status = http.StatusOK
+		writeJSON(w, rec.Status, map[string]interface{}{
+			"flood_risk":  sc.Risk,
+			"probability": sc.Probability,
+			"coords":      map[string]string{"lat": q.Get("latitude"), "lon": q.Get("longitude")},
+		})
+	}
+}
+
+// listRequests returns the calls received so far, oldest first; DELETE
+// clears the log between test cases.
+func (s *stub) listRequests(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		out := make([]recordedRequest, len(s.requests))
+		copy(out, s.requests)
+		writeJSON(w, http.StatusOK, out)
+	case http.MethodDelete:
+		s.requests = nil
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		writeJSON(w, http.StatusMethodNotAllowed, map[string]string{"error": "method not allowed"})
+	}
+}
+
+func (s *stub) record(rec recordedRequest) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) >= maxRecorded {
+		s.requests = s.requests[1:]
+	}
+	s.requests = append(s.requests, rec)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestLoadExampleScenarios(t *testing.T) {
+	set, err := loadScenarios("scenarios.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if set.Default.Name != "default" || set.Default.Risk != "low" || len(set.Scenarios) != 3 {
+		t.Fatalf("scenarios = %+v, want a low default and three scenarios", set)
+	}
+	tolerances := map[string]float64{"ho-chi-minh-city": defaultTolerance, "hanoi": 0.05, "da-nang-outage": defaultTolerance}
+	for _, sc := range set.Scenarios {
+		if sc.Tolerance != tolerances[sc.Name] {
+			t.Errorf("%s tolerance = %v, want %v", sc.Name, sc.Tolerance, tolerances[sc.Name])
+		}
+	}
+}
+
+func TestScenarioMatch(t *testing.T) {
+	set := &scenarioSet{
+		Default: scenario{Name: "default"},
+		Scenarios: []scenario{
+			{Name: "a", Lat: 10, Lon: 106, Tolerance: 0.5},
+			{Name: "b", Lat: 10.2, Lon: 106.2, Tolerance: 0.5},
+			{Name: "narrow", Lat: 21, Lon: 105, Tolerance: 0.01},
+		},
+	}
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"exact", 10, 106, "a"},
+		{"closest of two overlapping", 10.15, 106.15, "b"},
+		{"inside the tolerance", 21.009, 104.991, "narrow"},
+		{"just outside the tolerance", 21.02, 105, "default"},
+		{"far away", 50, 0, "default"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := set.match(tt.lat, tt.lon).Name; got != tt.want {
+				t.Errorf("match(%v, %v) = %s, want %s", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func newTestStub(t *testing.T) (*stub, *httptest.Server) {
+	t.Helper()
+	set, err := loadScenarios("scenarios.example.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &stub{scenarios: set, errorStatus: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(s.handler())
+	t.Cleanup(srv.Close)
+	return s, srv
+}
+
+func getJSON(t *testing.T, url string, out interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestRiskScenarios(t *testing.T) {
+	s, srv := newTestStub(t)
+	s.scenarios.Scenarios[1].LatencyMS = 0 // hanoi
+
+	tests := []struct {
+		name   string
+		query  string
+		status int
+		risk   string
+	}{
+		{"scenario", "latitude=10.8231&longitude=106.6297", 200, "high"},
+		{"within a wider tolerance", "latitude=21.06&longitude=105.85", 200, "medium"},
+		{"default", "latitude=48.85&longitude=2.35", 200, "low"},
+		{"error scenario", "latitude=16.0544&longitude=108.2022", 503, ""},
+		{"missing coordinates", "latitude=10", 400, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var body struct {
+				FloodRisk   string  `json:"flood_risk"`
+				Probability float64 `json:"probability"`
+			}
+			status := getJSON(t, srv.URL+"/risk?"+tt.query, &body)
+			if status != tt.status || body.FloodRisk != tt.risk {
+				t.Errorf("got %d %q, want %d %q", status, body.FloodRisk, tt.status, tt.risk)
+			}
+		})
+	}
+
+	var recorded []recordedRequest
+	getJSON(t, srv.URL+"/requests", &recorded)
+	if len(recorded) != len(tests) {
+		t.Fatalf("recorded %d requests, want %d", len(recorded), len(tests))
+	}
+	if r := recorded[3]; r.Scenario != "da-nang-outage" || r.Status != 503 {
+		t.Errorf("recorded %+v, want da-nang-outage with 503", r)
+	}
+	req, _ := http.NewRequest(http.MethodDelete, srv.URL+"/requests", nil)
+	if resp, err := http.DefaultClient.Do(req); err != nil || resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("DELETE /requests = %v, %v", resp, err)
+	}
+	getJSON(t, srv.URL+"/requests", &recorded)
+	if len(recorded) != 0 {
+		t.Errorf("recorded %d requests after DELETE, want none", len(recorded))
+	}
+}
+
+func TestRiskLatencyStopsWhenClientLeaves(t *testing.T) {
+	s, _ := newTestStub(t)
+	s.latency = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req := httptest.NewRequest(http.MethodGet, "/risk?latitude=10.8231&longitude=106.6297", nil).WithContext(ctx)
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		s.handler().ServeHTTP(w, req)
+		close(done)
+	}()
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("handler still sleeping after the client left")
+	}
+	if len(s.requests) != 1 || s.requests[0].Status != statusClientClosedRequest {
+		t.Errorf("recorded %+v, want one call closed by the client", s.requests)
+	}
+}
+
+func TestRiskInjectedErrors(t *testing.T) {
+	s, srv := newTestStub(t)
+	s.errorRate = 1
+	s.errorStatus = http.StatusBadGateway
+	if status := getJSON(t, srv.URL+"/risk?latitude=10.8231&longitude=106.6297", nil); status != http.StatusBadGateway {
+		t.Errorf("status %d, want %d", status, http.StatusBadGateway)
+	}
+}
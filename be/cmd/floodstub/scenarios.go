@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultTolerance is how far, in degrees, a request may be from a scenario's
+// coordinates and still match it.
+const defaultTolerance = 0.01
+
+// scenario is the canned answer for requests near Lat/Lon.
+type scenario struct {
+	Name        string  `json:"name" yaml:"name"`
+	Lat         float64 `json:"lat" yaml:"lat"`
+	Lon         float64 `json:"lon" yaml:"lon"`
+	Tolerance   float64 `json:"tolerance" yaml:"tolerance"`
+	Risk        string  `json:"risk" yaml:"risk"`
+	Probability float64 `json:"probability" yaml:"probability"`
+	Status      int     `json:"status" yaml:"status"`
+	LatencyMS   int     `json:"latency_ms" yaml:"latency_ms"`
+}
+
+// scenarioSet is the scenarios file: Default answers requests that match no
+// scenario.
+type scenarioSet struct {
+	Default   scenario   `json:"default" yaml:"default"`
+	Scenarios []scenario `json:"scenarios" yaml:"scenarios"`
+}
+
+// defaultScenarios reproduces the stub's original fixed answer.
+func defaultScenarios() *scenarioSet {
+	return &scenarioSet{Default: scenario{Name: "default", Risk: "high", Probability: 0.82}}
+}
+
+// loadScenarios reads a scenarios file; .yaml/.yml files are parsed as YAML,
+// anything else as JSON.
+func loadScenarios(path string) (*scenarioSet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	set := defaultScenarios()
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, set)
+	default:
+		err = json.Unmarshal(raw, set)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid scenarios file %s: %w", path, err)
+	}
+	if set.Default.Name == "" {
+		set.Default.Name = "default"
+	}
+	for i := range set.Scenarios {
+		sc := &set.Scenarios[i]
+		if sc.Name == "" {
+			sc.Name = fmt.Sprintf("%.4f,%.4f", sc.Lat, sc.Lon)
+		}
+		if sc.Tolerance <= 0 {
+			sc.Tolerance = defaultTolerance
+		}
+	}
+	return set, nil
+}
+
+// match returns the closest scenario within its tolerance of the point, or
+// the default.
+func (s *scenarioSet) match(lat, lon float64) scenario {
+	best, bestDist := s.Default, math.Inf(1)
+	for _, sc := range s.Scenarios {
+		d := math.Max(math.Abs(sc.Lat-lat), math.Abs(sc.Lon-lon))
+		if d <= sc.Tolerance && d < bestDist {
+			best, bestDist = sc, d
+		}
+	}
+	return best
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	timeLayout = "2006-01-02T15:04"
+	dateLayout = "2006-01-02"
+
+	defaultForecastDays = 7
+	maxForecastDays     = 16
+	maxPastDays         = 92
+)
+
+// forecastFixture describes the weather at one location. Hourly series hold
+// a daily profile (usually 24 values starting at local midnight) and daily
+// series a few days; both are repeated to cover the requested range.
+// Daily string values such as sunrise are local clock times ("06:05").
+type forecastFixture struct {
+	Name                 string  `json:"-"`
+	Latitude             float64 `json:"latitude"`
+	Longitude            float64 `json:"longitude"`
+	Elevation            float64 `json:"elevation"`
+	Timezone             string  `json:"timezone"`
+	TimezoneAbbreviation string  `json:"timezone_abbreviation"`
+	UTCOffsetSeconds     int     `json:"utc_offset_seconds"`
+	CurrentWeather       struct {
+		WeatherCode int `json:"weathercode"`
+	} `json:"current_weather"`
+	HourlyUnits map[string]string        `json:"hourly_units"`
+	Hourly      map[string][]float64     `json:"hourly"`
+	DailyUnits  map[string]string        `json:"daily_units"`
+	Daily       map[string][]interface{} `json:"daily"`
+	// Stub makes every request answered by this fixture slow or failing.
+	Stub struct {
+		Status    int `json:"status"`
+		LatencyMS int `json:"latency_ms"`
+	} `json:"stub"`
+}
+
+func loadForecastFixtures(fsys fs.FS) ([]forecastFixture, error) {
+	names, err := fs.Glob(fsys, "forecast/*.json")
+	if err != nil {
+		return nil, err
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no forecast/*.json fixtures")
+	}
+	out := make([]forecastFixture, 0, len(names))
+	for _, name := range names {
+		raw, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, err
+		}
+		var f forecastFixture
+		if err := json.Unmarshal(raw, &f); err != nil {
+			return nil, fmt.Errorf("invalid fixture %s: %w", name, err)
+		}
+		f.Name = strings.TrimSuffix(path.Base(name), ".json")
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// nearest returns the fixture closest to the coordinates; Open-Meteo answers
+// for any point, so there is always one.
+func (s *stub) nearest(lat, lon float64) forecastFixture {
+	best, bestDist := s.forecasts[0], math.Inf(1)
+	for _, f := range s.forecasts {
+		if d := math.Hypot(f.Latitude-lat, f.Longitude-lon); d < bestDist {
+			best, bestDist = f, d
+		}
+	}
+	return best
+}
+
+func (s *stub) forecast(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	lat, latErr := strconv.ParseFloat(q.Get("latitude"), 64)
+	lon, lonErr := strconv.ParseFloat(q.Get("longitude"), 64)
+	if latErr != nil || lonErr != nil || math.Abs(lat) > 90 || math.Abs(lon) > 180 {
+		writeError(w, http.StatusBadRequest, "Parameter latitude and longitude must be valid coordinates.")
+		return
+	}
+	pastDays, ok := intParam(q.Get("past_days"), 0, 0, maxPastDays)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Parameter 'past_days' must be between 0 and %d.", maxPastDays))
+		return
+	}
+	days, ok := intParam(q.Get("forecast_days"), defaultForecastDays, 0, maxForecastDays)
+	if !ok {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("Parameter 'forecast_days' must be between 0 and %d.", maxForecastDays))
+		return
+	}
+
+	f := s.nearest(lat, lon)
+	if f.Stub.LatencyMS > 0 {
+		time.Sleep(time.Duration(f.Stub.LatencyMS) * time.Millisecond)
+	}
+	if f.Stub.Status >= 400 {
+		writeError(w, f.Stub.Status, "fixture "+f.Name)
+		return
+	}
+
+	// Without timezone=auto Open-Meteo reports GMT.
+	tz, abbr, offset := "GMT", "GMT", 0
+	if q.Get("timezone") == "auto" {
+		tz, abbr, offset = f.Timezone, f.TimezoneAbbreviation, f.UTCOffsetSeconds
+	}
+	loc := time.FixedZone(abbr, offset)
+	now := time.Now().In(loc)
+	start := time.Date(now.Year(), now.Month(), now.Day()-pastDays, 0, 0, 0, 0, loc)
+	totalDays := pastDays + days
+
+	resp := map[string]interface{}{
+		"latitude":              f.Latitude,
+		"longitude":             f.Longitude,
+		"generationtime_ms":     0.5,
+		"utc_offset_seconds":    offset,
+		"timezone":              tz,
+		"timezone_abbreviation": abbr,
+		"elevation":             f.Elevation,
+	}
+	if vars := requested(q.Get("hourly")); len(vars) > 0 {
+		times := make([]string, totalDays*24)
+		for i := range times {
+			times[i] = start.Add(time.Duration(i) * time.Hour).Format(timeLayout)
+		}
+		units, series := map[string]string{"time": "iso8601"}, map[string]interface{}{"time": times}
+		for _, v := range vars {
+			if profile, ok := f.Hourly[v]; ok && len(profile) > 0 {
+				series[v] = repeatFloats(profile, len(times))
+				units[v] = f.HourlyUnits[v]
+			}
+		}
+		resp["hourly_units"], resp["hourly"] = units, series
+	}
+	if vars := requested(q.Get("daily")); len(vars) > 0 {
+		dates := make([]string, totalDays)
+		for i := range dates {
+			dates[i] = start.AddDate(0, 0, i).Format(dateLayout)
+		}
+		units, series := map[string]string{"time": "iso8601"}, map[string]interface{}{"time": dates}
+		for _, v := range vars {
+			if vals, ok := f.Daily[v]; ok && len(vals) > 0 {
+				series[v] = dailySeries(vals, dates)
+				units[v] = f.DailyUnits[v]
+			}
+		}
+		resp["daily_units"], resp["daily"] = units, series
+	}
+	if q.Get("current_weather") == "true" {
+		resp["current_weather"] = f.current(now)
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// current builds current_weather from the hourly profile at now's hour.
+func (f forecastFixture) current(now time.Time) map[string]interface{} {
+	hour := time.Date(now.Year(), now.Month(), now.Day(), now.Hour(), 0, 0, 0, now.Location())
+	at := func(name string) float64 {
+		profile := f.Hourly[name]
+		if len(profile) == 0 {
+			return 0
+		}
+		return profile[now.Hour()%len(profile)]
+	}
+	isDay := 0
+	if now.Hour() >= 6 && now.Hour() < 18 {
+		isDay = 1
+	}
+	return map[string]interface{}{
+		"time":          hour.Format(timeLayout),
+		"interval":      3600,
+		"temperature":   at("temperature_2m"),
+		"windspeed":     at("windspeed_10m"),
+		"winddirection": at("winddirection_10m"),
+		"is_day":        isDay,
+		"weathercode":   f.CurrentWeather.WeatherCode,
+	}
+}
+
+// requested splits a comma-separated variable list.
+func requested(list string) []string {
+	var out []string
+	for _, v := range strings.Split(list, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func repeatFloats(profile []float64, n int) []float64 {
+	out := make([]float64, n)
+	for i := range out {
+		out[i] = profile[i%len(profile)]
+	}
+	return out
+}
+
+// dailySeries repeats vals over dates; string values are clock times and are
+// placed on their day.
+func dailySeries(vals []interface{}, dates []string) []interface{} {
+	out := make([]interface{}, len(dates))
+	for i, date := range dates {
+		v := vals[i%len(vals)]
+		if clock, ok := v.(string); ok {
+			v = date + "T" + clock
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// intParam parses an optional integer query parameter within [min, max].
+func intParam(raw string, def, min, max int) (int, bool) {
+	if raw == "" {
+		return def, true
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < min || n > max {
+		return 0, false
+	}
+	return n, true
+}
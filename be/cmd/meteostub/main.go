@@ -0,0 +1,99 @@
+// Command meteostub serves Open-Meteo compatible /v1/forecast and /v1/search
+// responses from fixture files, so weatherd's real provider and city search
+// code paths can run offline. Point WEATHER_API_URL and GEOCODE_API_URL at it:
+//
+//	go run ./cmd/meteostub -port 9100
+//	WEATHER_API_URL=http://localhost:9100/v1/forecast \
+//	GEOCODE_API_URL=http://localhost:9100/v1/search ./bin/weatherd
+//
+// Forecast timestamps are generated around the current time, so cached
+// results, forecasts and flood windows behave as they would live.
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"flag"
+	"io/fs"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+//go:embed fixtures
+var embeddedFixtures embed.FS
+
+// stub holds the loaded fixtures and the injection settings.
+type stub struct {
+	forecasts   []forecastFixture
+	places      []place
+	latency     time.Duration
+	errorRate   float64
+	errorStatus int
+}
+
+func main() {
+	port := flag.Int("port", 9100, "port to listen on")
+	dir := flag.String("fixtures", "", "fixtures directory with search.json and forecast/*.json (default: built-in fixtures)")
+	latency := flag.Duration("latency", 0, "delay added to every response")
+	errorRate := flag.Float64("error-rate", 0, "fraction (0-1) of calls answered with -error-status")
+	errorStatus := flag.Int("error-status", http.StatusServiceUnavailable, "status code for injected errors")
+	flag.Parse()
+
+	var fsys fs.FS
+	if *dir != "" {
+		fsys = os.DirFS(*dir)
+	} else {
+		fsys, _ = fs.Sub(embeddedFixtures, "fixtures")
+	}
+	forecasts, err := loadForecastFixtures(fsys)
+	if err != nil {
+		log.Fatalf("failed to load forecast fixtures: %v", err)
+	}
+	places, err := loadPlaces(fsys)
+	if err != nil {
+		log.Fatalf("failed to load search fixtures: %v", err)
+	}
+	s := &stub{forecasts: forecasts, places: places, latency: *latency, errorRate: *errorRate, errorStatus: *errorStatus}
+
+	addr := ":" + strconv.Itoa(*port)
+	log.Printf("meteo stub listening on %s (%d forecast fixtures, %d places)", addr, len(forecasts), len(places))
+	log.Fatal(http.ListenAndServe(addr, s.handler()))
+}
+
+// handler routes the Open-Meteo endpoints.
+func (s *stub) handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/forecast", s.inject(s.forecast))
+	mux.HandleFunc("/v1/search", s.inject(s.search))
+	return mux
+}
+
+// inject applies the configured latency and random errors before next runs.
+func (s *stub) inject(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		log.Printf("%s %s", r.Method, r.URL.RequestURI())
+		if s.latency > 0 {
+			time.Sleep(s.latency)
+		}
+		if s.errorRate > 0 && rand.Float64() < s.errorRate {
+			writeError(w, s.errorStatus, "injected error")
+			return
+		}
+		next(w, r)
+	}
+}
+
+// writeError answers in Open-Meteo's error format.
+func writeError(w http.ResponseWriter, status int, reason string) {
+	writeJSON(w, status, map[string]interface{}{"error": true, "reason": reason})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
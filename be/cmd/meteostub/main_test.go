@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"io/fs"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+// newTestStub serves the built-in fixtures.
+func newTestStub(t *testing.T) *httptest.Server {
+	t.Helper()
+	fsys, _ := fs.Sub(embeddedFixtures, "fixtures")
+	forecasts, err := loadForecastFixtures(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	places, err := loadPlaces(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer((&stub{forecasts: forecasts, places: places}).handler())
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func getJSON(t *testing.T, url string, out interface{}) int {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestNearestFixture(t *testing.T) {
+	s := &stub{forecasts: []forecastFixture{
+		{Name: "hanoi", Latitude: 21, Longitude: 105.875},
+		{Name: "ho-chi-minh-city", Latitude: 10.75, Longitude: 106.625},
+		{Name: "oslo", Latitude: 59.9, Longitude: 10.75},
+	}}
+	tests := []struct {
+		lat, lon float64
+		want     string
+	}{
+		{21.0285, 105.8542, "hanoi"},
+		{10.8231, 106.6297, "ho-chi-minh-city"},
+		{12.2388, 109.1967, "ho-chi-minh-city"},
+		{51.5074, -0.1278, "oslo"},
+	}
+	for _, tt := range tests {
+		if got := s.nearest(tt.lat, tt.lon).Name; got != tt.want {
+			t.Errorf("nearest(%v, %v) = %s, want %s", tt.lat, tt.lon, got, tt.want)
+		}
+	}
+}
+
+type forecastBody struct {
+	Latitude         float64                      `json:"latitude"`
+	UTCOffsetSeconds int                          `json:"utc_offset_seconds"`
+	Timezone         string                       `json:"timezone"`
+	HourlyUnits      map[string]string            `json:"hourly_units"`
+	Hourly           map[string][]json.RawMessage `json:"hourly"`
+	Daily            map[string][]json.RawMessage `json:"daily"`
+	CurrentWeather   map[string]interface{}       `json:"current_weather"`
+}
+
+func TestForecast(t *testing.T) {
+	srv := newTestStub(t)
+	var body forecastBody
+	status := getJSON(t, srv.URL+"/v1/forecast?latitude=21.03&longitude=105.85&timezone=auto"+
+		"&past_days=2&forecast_days=3&hourly=temperature_2m,precipitation,unknown_var"+
+		"&daily=sunrise,temperature_2m_max&current_weather=true", &body)
+	if status != http.StatusOK {
+		t.Fatalf("status %d", status)
+	}
+	if body.Latitude != 21 || body.Timezone != "Asia/Bangkok" || body.UTCOffsetSeconds != 25200 {
+		t.Errorf("answered from %v %s%+d, want the Hanoi fixture in its own timezone", body.Latitude, body.Timezone, body.UTCOffsetSeconds)
+	}
+
+	// Only the requested variables the fixture has are returned.
+	if len(body.Hourly) != 3 || body.Hourly["temperature_2m"] == nil || body.Hourly["precipitation"] == nil {
+		t.Errorf("hourly variables %v, want time, temperature_2m and precipitation", keys(body.Hourly))
+	}
+	if body.HourlyUnits["temperature_2m"] == "" || body.HourlyUnits["unknown_var"] != "" {
+		t.Errorf("hourly units %v", body.HourlyUnits)
+	}
+	if len(body.Daily) != 3 {
+		t.Errorf("daily variables %v, want time, sunrise and temperature_2m_max", keys(body.Daily))
+	}
+
+	// Two past days and three forecast days, from local midnight.
+	times := strs(body.Hourly["time"])
+	if len(times) != 5*24 {
+		t.Fatalf("%d hourly times, want %d", len(times), 5*24)
+	}
+	first, err := time.Parse(timeLayout, times[0])
+	if err != nil || first.Hour() != 0 {
+		t.Fatalf("first hour %s, want a midnight", times[0])
+	}
+	if last, _ := time.Parse(timeLayout, times[len(times)-1]); last.Sub(first) != (5*24-1)*time.Hour {
+		t.Errorf("hours run from %s to %s", times[0], times[len(times)-1])
+	}
+	for _, v := range []string{"temperature_2m", "precipitation"} {
+		if len(body.Hourly[v]) != len(times) {
+			t.Errorf("%s has %d values, want %d", v, len(body.Hourly[v]), len(times))
+		}
+	}
+	if len(body.Daily["time"]) != 5 || len(body.Daily["sunrise"]) != 5 {
+		t.Fatalf("daily series %d/%d long, want 5", len(body.Daily["time"]), len(body.Daily["sunrise"]))
+	}
+	date, sunrise := strs(body.Daily["time"])[0], strs(body.Daily["sunrise"])[0]
+	if date != first.Format(dateLayout) || !strings.HasPrefix(sunrise, date+"T") {
+		t.Errorf("first day %s with sunrise %s, want %s", date, sunrise, first.Format(dateLayout))
+	}
+	if body.CurrentWeather["temperature"] == nil {
+		t.Errorf("current_weather = %v", body.CurrentWeather)
+	}
+}
+
+func TestForecastDefaults(t *testing.T) {
+	srv := newTestStub(t)
+	var body forecastBody
+	getJSON(t, srv.URL+"/v1/forecast?latitude=59.9&longitude=10.75&hourly=temperature_2m", &body)
+	if body.Timezone != "GMT" || body.UTCOffsetSeconds != 0 {
+		t.Errorf("timezone %s%+d, want GMT without timezone=auto", body.Timezone, body.UTCOffsetSeconds)
+	}
+	if n := len(body.Hourly["time"]); n != defaultForecastDays*24 {
+		t.Errorf("%d hourly times, want %d", n, defaultForecastDays*24)
+	}
+	if body.Daily != nil || body.CurrentWeather != nil {
+		t.Error("returned series that were not requested")
+	}
+}
+
+func TestForecastRejectsBadParams(t *testing.T) {
+	srv := newTestStub(t)
+	for _, q := range []string{
+		"latitude=91&longitude=0",
+		"latitude=21&longitude=east",
+		"latitude=21&longitude=105&past_days=93",
+		"latitude=21&longitude=105&forecast_days=17",
+		"latitude=21&longitude=105&forecast_days=-1",
+	} {
+		var body struct {
+			Error  bool   `json:"error"`
+			Reason string `json:"reason"`
+		}
+		if status := getJSON(t, srv.URL+"/v1/forecast?"+q, &body); status != http.StatusBadRequest || !body.Error {
+			t.Errorf("%s: status %d %+v, want 400 in Open-Meteo's error format", q, status, body)
+		}
+	}
+}
+
+func TestForecastFixtureStatus(t *testing.T) {
+	fsys := fstest.MapFS{
+		"forecast/outage.json": {Data: []byte(`{"latitude": 1, "longitude": 1, "stub": {"status": 502}}`)},
+	}
+	forecasts, err := loadForecastFixtures(fsys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer((&stub{forecasts: forecasts}).handler())
+	defer srv.Close()
+	var body map[string]interface{}
+	if status := getJSON(t, srv.URL+"/v1/forecast?latitude=1&longitude=1", &body); status != http.StatusBadGateway || body["reason"] != "fixture outage" {
+		t.Errorf("status %d %v, want the fixture's 502", status, body)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := newTestStub(t)
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"name=ha", []string{"Hanoi", "Haiphong", "Hamburg"}},
+		{"name=HA&count=2", []string{"Hanoi", "Haiphong"}},
+		{"name=%20oslo%20", []string{"Oslo"}},
+		{"name=zz", nil},
+		{"name=", nil},
+	}
+	for _, tt := range tests {
+		var body struct {
+			Results []place `json:"results"`
+		}
+		if status := getJSON(t, srv.URL+"/v1/search?"+tt.query, &body); status != http.StatusOK {
+			t.Fatalf("%s: status %d", tt.query, status)
+		}
+		var got []string
+		for _, p := range body.Results {
+			got = append(got, p.Name)
+		}
+		if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	var body map[string]interface{}
+	if status := getJSON(t, srv.URL+"/v1/search?name=ha&count=0", &body); status != http.StatusBadRequest {
+		t.Errorf("count=0: status %d, want 400", status)
+	}
+}
+
+func keys(m map[string][]json.RawMessage) []string {
+	var out []string
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
+
+// strs decodes a series of strings, leaving "" for other values.
+func strs(vals []json.RawMessage) []string {
+	out := make([]string, len(vals))
+	for i, v := range vals {
+		_ = json.Unmarshal(v, &out[i])
+	}
+	return out
+}
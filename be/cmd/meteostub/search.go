@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	defaultSearchCount = 10
+	maxSearchCount     = 100
+)
+
+// place is one geocoding result as returned by /v1/search.
+type place struct {
+	ID          int     `json:"id"`
+	Name        string  `json:"name"`
+	Latitude    float64 `json:"latitude"`
+	Longitude   float64 `json:"longitude"`
+	Elevation   float64 `json:"elevation"`
+	FeatureCode string  `json:"feature_code"`
+	CountryCode string  `json:"country_code"`
+	Timezone    string  `json:"timezone"`
+	Population  int     `json:"population"`
+	Country     string  `json:"country"`
+	Admin1      string  `json:"admin1,omitempty"`
+}
+
+func loadPlaces(fsys fs.FS) ([]place, error) {
+	raw, err := fs.ReadFile(fsys, "search.json")
+	if err != nil {
+		return nil, err
+	}
+	var places []place
+	if err := json.Unmarshal(raw, &places); err != nil {
+		return nil, fmt.Errorf("invalid search.json: %w", err)
+	}
+	// Like Open-Meteo, rank larger places first.
+	sort.SliceStable(places, func(i, j int) bool { return places[i].Population > places[j].Population })
+	return places, nil
+}
+
+// search returns the places whose name starts with the name parameter,
+// ignoring case. As upstream, a query without matches has no results key.
+func (s *stub) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	name := strings.TrimSpace(q.Get("name"))
+	count := defaultSearchCount
+	if v := q.Get("count"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxSearchCount {
+			writeError(w, http.StatusBadRequest, "Parameter count must be between 1 and 100.")
+			return
+		}
+		count = n
+	}
+
+	resp := map[string]interface{}{"generationtime_ms": 0.5}
+	if name == "" {
+		writeJSON(w, http.StatusOK, resp)
+		return
+	}
+	var results []place
+	for _, p := range s.places {
+		if len(results) == count {
+			break
+		}
+		if strings.HasPrefix(strings.ToLower(p.Name), strings.ToLower(name)) {
+			results = append(results, p)
+		}
+	}
+	if len(results) > 0 {
+		resp["results"] = results
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
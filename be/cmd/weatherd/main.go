@@ -1,40 +1,202 @@
 package main
 
 import (
+	"context"
 	"fmt"
-	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	_ "github.com/jeffhieun/weatherdatadashboard/docs"
 	"github.com/jeffhieun/weatherdatadashboard/internal/api"
+	"github.com/jeffhieun/weatherdatadashboard/internal/cassette"
 	"github.com/jeffhieun/weatherdatadashboard/internal/config"
+	"github.com/jeffhieun/weatherdatadashboard/internal/flood"
+	"github.com/jeffhieun/weatherdatadashboard/internal/metrics"
+	"github.com/jeffhieun/weatherdatadashboard/internal/retention"
 	"github.com/jeffhieun/weatherdatadashboard/internal/service"
 	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
+// version is set at build time with -ldflags "-X main.version=...".
+var version = "dev"
+
 func main() {
 	cfg := config.Load()
-	repo := store.NewInMemoryRepository()
-	weatherSvc := service.NewDefaultWeatherService(repo, time.Duration(cfg.CacheTTL)*time.Second)
-	geocodeSvc := service.NewGeocodeService(repo, time.Duration(cfg.CacheTTL)*time.Second)
-	h := api.NewHandler(weatherSvc, geocodeSvc)
+	util.SetupLogger(cfg.LogLevel, cfg.LogFormat)
+	shutdownTracing := func(context.Context) error { return nil }
+	if cfg.OTLPEndpoint != "" {
+		shutdown, err := tracing.Setup(context.Background(), version)
+		if err != nil {
+			fatal("failed to set up tracing", err)
+		}
+		shutdownTracing = shutdown
+		util.Logger.Info("exporting traces", "endpoint", cfg.OTLPEndpoint)
+	}
+	repo, err := newRepository(cfg)
+	if err != nil {
+		fatal("failed to open repository", err)
+	}
+	metrics.RegisterRepository(repo)
+	metrics.RegisterWeather(repo, cfg.MetricsCities)
+	repo = tracing.InstrumentRepository(metrics.InstrumentRepository(repo))
+	httpClient, err := newHTTPClient(cfg)
+	if err != nil {
+		fatal("failed to set up upstream client", err)
+	}
+	upstream := service.NewUpstreamClient(httpClient, service.UpstreamOptions{
+		Timeout:          time.Duration(cfg.UpstreamTimeout) * time.Second,
+		MaxRetries:       cfg.UpstreamRetries,
+		BreakerThreshold: cfg.BreakerThreshold,
+		BreakerCooldown:  time.Duration(cfg.BreakerCooldown) * time.Second,
+	})
+	provider := service.NewOpenMeteoProvider(cfg.GeocodeAPIURL, cfg.WeatherAPIURL, upstream)
+	weatherSvc := service.NewDefaultWeatherService(repo, provider, time.Duration(cfg.CacheTTL)*time.Second)
+	if cfg.CacheStaleTTL > 0 {
+		weatherSvc.EnableStaleWhileRevalidate(time.Duration(cfg.CacheStaleTTL)*time.Second, cfg.RefreshWorkers)
+	}
+	geocodeSvc := service.NewGeocodeService(repo, time.Duration(cfg.CacheTTL)*time.Second, cfg.GeocodeAPIURL, upstream)
+	zones, err := loadFloodZones(cfg)
+	if err != nil {
+		fatal("failed to load flood zones", err)
+	}
+	floodSvc := flood.NewService(provider, repo, zones)
+	probes := []string{cfg.WeatherAPIURL, cfg.GeocodeAPIURL}
+	if cfg.FloodAPIURL != "" {
+		floodSvc.UseRiskAPI(service.NewFloodRiskClient(cfg.FloodAPIURL, upstream))
+		probes = append(probes, cfg.FloodAPIURL)
+	}
+	compactor := retention.NewCompactor(repo, retention.Policy{
+		RawAge:    time.Duration(cfg.HistoryRawAge) * time.Second,
+		HourlyAge: time.Duration(cfg.HistoryHourlyAge) * time.Second,
+		MaxAge:    time.Duration(cfg.HistoryMaxAge) * time.Second,
+		MaxCount:  cfg.HistoryMaxPerCity,
 
-	r := gin.Default()
+		FloodMaxAge:   time.Duration(cfg.FloodResultsMaxAge) * time.Second,
+		FloodMaxCount: cfg.FloodResultsMaxPerCity,
+	})
+	if cfg.HistoryCompactSeconds > 0 {
+		compactor.Start(time.Duration(cfg.HistoryCompactSeconds) * time.Second)
+	}
+	h := api.NewHandler(weatherSvc, geocodeSvc, floodSvc, upstream)
+	health := api.NewHealthHandler(repo, upstream, probes, version)
+
+	r := gin.New()
+	r.Use(gin.Recovery(), api.RequestID(), tracing.Middleware(), api.AccessLog(), metrics.Middleware())
 
 	r.GET("/api/weather/details", h.GetWeatherDetails)
 	r.GET("/api/weather/current", h.GetWeatherDetails) // Backward compatibility
+	r.GET("/api/weather/forecast", h.GetForecast)
 	r.GET("/api/weather/result", h.GetCachedResult)
 	r.GET("/api/weather/results", h.ListCachedResults)
 	r.GET("/api/cities/search", h.SearchCities)
 	r.GET("/api/flood/risk", h.FloodRisk)
+	r.GET("/api/flood/current", h.FloodCurrent)
+	r.GET("/api/flood/result", h.FloodResult)
 	r.GET("/api/flood/results", h.ListFloodResults)
+	r.GET("/api/flood/zones", h.FloodZones)
+	if cfg.AdminToken == "" {
+		util.Logger.Warn("ADMIN_TOKEN is not set, /api/admin/* is open to anyone")
+	}
+	admin := r.Group("/api/admin", api.AdminAuth(cfg.AdminToken))
+	admin.GET("/upstreams", h.UpstreamStatus)
+	admin.GET("/diagnostics", health.Diagnostics)
+	r.GET("/healthz", health.Healthz)
+	r.GET("/readyz", health.Readyz)
+	r.GET("/metrics", gin.WrapH(metrics.Handler()))
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
-	log.Printf("starting weatherd on :%s", cfg.Port)
-	if err := r.Run(fmt.Sprintf(":%s", cfg.Port)); err != nil {
-		log.Fatalf("server failed: %v", err)
+	srv := &http.Server{
+		Addr:              fmt.Sprintf(":%s", cfg.Port),
+		Handler:           r,
+		ReadTimeout:       time.Duration(cfg.ReadTimeout) * time.Second,
+		ReadHeaderTimeout: time.Duration(cfg.ReadHeaderTimeout) * time.Second,
+		WriteTimeout:      time.Duration(cfg.WriteTimeout) * time.Second,
+		IdleTimeout:       time.Duration(cfg.IdleTimeout) * time.Second,
+	}
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+	util.Logger.Info("starting weatherd", "port", cfg.Port, "version", version)
+	select {
+	case err := <-serveErr:
+		fatal("server failed", err)
+	case <-ctx.Done():
+	}
+	// A second signal kills the process without waiting for the drain.
+	stop()
+
+	util.Logger.Info("shutting down", "timeout_s", cfg.ShutdownTimeout)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.ShutdownTimeout)*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		// Closing the remaining connections cancels their requests.
+		util.Logger.Warn("connections did not drain in time", "err", err)
+		srv.Close()
+	}
+	if err := weatherSvc.Shutdown(shutdownCtx); err != nil {
+		util.Logger.Warn("background refreshes cancelled", "err", err)
+	}
+	compactor.Stop()
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		util.Logger.Warn("failed to flush traces", "err", err)
 	}
+	repo.Close()
+	util.Logger.Info("stopped")
+}
+
+// newRepository picks the shared Redis store when REDIS_URL is set, the
+// on-disk store when STORE_PATH is set, and falls back to the process-local
+// in-memory store otherwise.
+func newRepository(cfg config.Config) (store.WeatherRepository, error) {
+	if cfg.RedisURL != "" {
+		util.Logger.Info("using redis repository")
+		return store.NewRedisRepository(cfg.RedisURL)
+	}
+	if cfg.StorePath != "" {
+		util.Logger.Info("using on-disk repository", "path", cfg.StorePath)
+		return store.NewBoltRepository(cfg.StorePath)
+	}
+	return store.NewInMemoryRepository(), nil
+}
+
+// newHTTPClient returns the client for upstream calls, wrapped in a cassette
+// recorder when UPSTREAM_CASSETTE_MODE is record or replay.
+func newHTTPClient(cfg config.Config) (*http.Client, error) {
+	if cfg.CassetteMode == "" {
+		return http.DefaultClient, nil
+	}
+	rec, err := cassette.New(cassette.Mode(cfg.CassetteMode), cfg.CassetteDir, nil)
+	if err != nil {
+		return nil, err
+	}
+	util.Logger.Info("using upstream cassette", "mode", cfg.CassetteMode, "dir", cfg.CassetteDir)
+	return &http.Client{Transport: rec}, nil
+}
+
+// loadFloodZones reads the GeoJSON zone map from FLOOD_ZONES_PATH, if set.
+func loadFloodZones(cfg config.Config) (*flood.ZoneIndex, error) {
+	if cfg.FloodZonesPath == "" {
+		return nil, nil
+	}
+	zones, err := flood.LoadZones(cfg.FloodZonesPath)
+	if err != nil {
+		return nil, err
+	}
+	util.Logger.Info("loaded flood zones", "count", zones.Len(), "path", cfg.FloodZonesPath)
+	return zones, nil
+}
+
+// fatal logs err and exits.
+func fatal(msg string, err error) {
+	util.Logger.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -15,6 +15,55 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/admin/diagnostics": {
+            "get": {
+                "description": "Reports build version, uptime, repository reachability and sizes, upstream reachability and circuit breaker states. Upstream probes are reused for 10 seconds.",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Dependency diagnostics",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.Diagnostics"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/upstreams": {
+            "get": {
+                "description": "Returns the circuit breaker state for every upstream host called so far",
+                "tags": [
+                    "admin"
+                ],
+                "summary": "Upstream circuit breakers",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/service.BreakerStatus"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
         "/api/cities/search": {
             "get": {
                 "description": "Returns up to 5 city suggestions for the given query using Open-Meteo geocoding",
@@ -44,19 +93,109 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/flood/current": {
+            "get": {
+                "description": "Geocodes the city and returns a freshly computed flood risk (recorded like /api/flood/risk)",
+                "tags": [
+                    "flood"
+                ],
+                "summary": "Get current flood risk for a city",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "City name",
+                        "name": "city",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
                         "schema": {
                             "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/flood/result": {
+            "get": {
+                "description": "Returns the most recently stored flood risk for a city (no live fetch)",
+                "tags": [
+                    "flood"
+                ],
+                "summary": "Get latest flood result for a city",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "City name",
+                        "name": "city",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
@@ -64,11 +203,37 @@ const docTemplate = `{
         },
         "/api/flood/results": {
             "get": {
-                "description": "Returns all cached flood risk records (stubbed)",
+                "description": "Returns recorded flood risk assessments (city, coordinates, risk, probability, fetched_at) with optional filters",
                 "tags": [
                     "flood"
                 ],
-                "summary": "List all cached flood results (stub)",
+                "summary": "List stored flood results",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "City name",
+                        "name": "city",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Day of month",
+                        "name": "day",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Month (1-12)",
+                        "name": "month",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Year (e.g., 2025)",
+                        "name": "year",
+                        "in": "query"
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
@@ -79,17 +244,23 @@ const docTemplate = `{
                                 "additionalProperties": true
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
                     }
                 }
             }
         },
         "/api/flood/risk": {
             "get": {
-                "description": "Returns a dynamic flood risk for given latitude and longitude",
+                "description": "Returns the flood risk for given latitude and longitude, computed from past and forecast precipitation, precipitation probability and snowmelt, together with any mapped flood zones containing the point. flood_risk/probability mirror level/score for older clients.",
                 "tags": [
                     "flood"
                 ],
-                "summary": "Get flood risk (dynamic demo)",
+                "summary": "Get flood risk",
                 "parameters": [
                     {
                         "type": "string",
@@ -104,6 +275,12 @@ const docTemplate = `{
                         "name": "longitude",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "City name recorded with the stored result",
+                        "name": "city",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -113,17 +290,117 @@ const docTemplate = `{
                             "type": "object",
                             "additionalProperties": true
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/flood/zones": {
+            "get": {
+                "description": "Returns the configured flood zone polygons intersecting bbox as a GeoJSON FeatureCollection, for map overlays",
+                "tags": [
+                    "flood"
+                ],
+                "summary": "List flood zones in a bounding box",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "minLon,minLat,maxLon,maxLat",
+                        "name": "bbox",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.FloodZoneCollection"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
                     }
                 }
             }
         },
         "/api/weather/current": {
             "get": {
-                "description": "Returns the current weather for a city (live fetch, caches result)",
+                "description": "Returns the current weather for a city, or for exact coordinates without geocoding (live fetch, caches result)",
                 "tags": [
                     "weather"
                 ],
                 "summary": "Get current weather",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "City name (required unless lat/lon are given; used as the label for coordinate lookups)",
+                        "name": "city",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Latitude",
+                        "name": "lat",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Longitude",
+                        "name": "lon",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/weather/forecast": {
+            "get": {
+                "description": "Returns the hourly series and daily aggregates for a city (cached like current conditions)",
+                "tags": [
+                    "weather"
+                ],
+                "summary": "Get hourly and daily forecast",
                 "parameters": [
                     {
                         "type": "string",
@@ -131,32 +408,49 @@ const docTemplate = `{
                         "name": "city",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Number of forecast days (1-16, default 7)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "hourly or daily (default both)",
+                        "name": "granularity",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": true
+                            "$ref": "#/definitions/model.Forecast"
                         }
                     },
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
@@ -189,19 +483,13 @@ const docTemplate = `{
                     "400": {
                         "description": "Bad Request",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     },
                     "404": {
                         "description": "Not Found",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/api.ErrorResponse"
                         }
                     }
                 }
@@ -250,6 +538,52 @@ const docTemplate = `{
                                 "additionalProperties": true
                             }
                         }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/healthz": {
+            "get": {
+                "description": "Returns 200 while the process is serving requests",
+                "tags": [
+                    "health"
+                ],
+                "summary": "Liveness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.HealthStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/readyz": {
+            "get": {
+                "description": "Returns 200 when the repository (memory, on-disk or Redis) is reachable, 503 otherwise",
+                "tags": [
+                    "health"
+                ],
+                "summary": "Readiness probe",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/api.HealthStatus"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.HealthStatus"
+                        }
                     }
                 }
             }
@@ -272,6 +606,267 @@ const docTemplate = `{
                     "type": "string"
                 }
             }
+        },
+        "api.Diagnostics": {
+            "type": "object",
+            "properties": {
+                "breakers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.BreakerStatus"
+                    }
+                },
+                "goVersion": {
+                    "type": "string"
+                },
+                "repository": {
+                    "$ref": "#/definitions/api.RepositoryDiagnostics"
+                },
+                "revision": {
+                    "type": "string"
+                },
+                "startedAt": {
+                    "type": "string"
+                },
+                "upstreams": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/service.ProbeResult"
+                    }
+                },
+                "uptimeSeconds": {
+                    "type": "integer"
+                },
+                "version": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ErrorBody": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string"
+                },
+                "details": {
+                    "type": "object",
+                    "additionalProperties": true
+                },
+                "message": {
+                    "type": "string"
+                }
+            }
+        },
+        "api.ErrorResponse": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "$ref": "#/definitions/api.ErrorBody"
+                }
+            }
+        },
+        "api.FloodZoneCollection": {
+            "type": "object",
+            "properties": {
+                "features": {
+                    "type": "array",
+                    "items": {
+                        "type": "object"
+                    }
+                },
+                "type": {
+                    "type": "string",
+                    "example": "FeatureCollection"
+                }
+            }
+        },
+        "api.HealthStatus": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "status": {
+                    "type": "string",
+                    "example": "ok"
+                }
+            }
+        },
+        "api.RepositoryDiagnostics": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "reachable": {
+                    "type": "boolean"
+                },
+                "stats": {
+                    "$ref": "#/definitions/store.Stats"
+                }
+            }
+        },
+        "model.DailyForecast": {
+            "type": "object",
+            "properties": {
+                "date": {
+                    "type": "string"
+                },
+                "precipSum": {
+                    "type": "number"
+                },
+                "sunrise": {
+                    "type": "string"
+                },
+                "sunset": {
+                    "type": "string"
+                },
+                "tempMax": {
+                    "type": "number"
+                },
+                "tempMin": {
+                    "type": "number"
+                },
+                "uvIndexMax": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.Forecast": {
+            "type": "object",
+            "properties": {
+                "city": {
+                    "type": "string"
+                },
+                "daily": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.DailyForecast"
+                    }
+                },
+                "hourly": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.HourlyForecast"
+                    }
+                },
+                "lat": {
+                    "type": "number"
+                },
+                "lon": {
+                    "type": "number"
+                },
+                "timezone": {
+                    "type": "string"
+                },
+                "updatedAt": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.HourlyForecast": {
+            "type": "object",
+            "properties": {
+                "cloudCover": {
+                    "type": "integer"
+                },
+                "feelsLike": {
+                    "type": "number"
+                },
+                "humidity": {
+                    "type": "integer"
+                },
+                "precipProb": {
+                    "type": "number"
+                },
+                "precipitation": {
+                    "type": "number"
+                },
+                "pressure": {
+                    "type": "integer"
+                },
+                "rain": {
+                    "type": "number"
+                },
+                "snow": {
+                    "type": "number"
+                },
+                "snowDepth": {
+                    "type": "number"
+                },
+                "temperature": {
+                    "type": "number"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "uvIndex": {
+                    "type": "number"
+                },
+                "visibility": {
+                    "type": "number"
+                },
+                "windDir": {
+                    "type": "string"
+                },
+                "windSpeed": {
+                    "type": "number"
+                }
+            }
+        },
+        "service.BreakerStatus": {
+            "type": "object",
+            "properties": {
+                "consecutiveFailures": {
+                    "type": "integer"
+                },
+                "host": {
+                    "type": "string"
+                },
+                "openUntil": {
+                    "type": "string"
+                },
+                "state": {
+                    "type": "string"
+                }
+            }
+        },
+        "service.ProbeResult": {
+            "type": "object",
+            "properties": {
+                "error": {
+                    "type": "string"
+                },
+                "latencyMs": {
+                    "type": "integer"
+                },
+                "reachable": {
+                    "type": "boolean"
+                },
+                "statusCode": {
+                    "type": "integer"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "store.Stats": {
+            "type": "object",
+            "properties": {
+                "cachedEntries": {
+                    "type": "integer"
+                },
+                "floodResults": {
+                    "type": "integer"
+                },
+                "historyCities": {
+                    "type": "integer"
+                },
+                "historySnapshots": {
+                    "type": "integer"
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// ErrorResponse is the envelope every endpoint uses to report an error.
+type ErrorResponse struct {
+	Error ErrorBody `json:"error"`
+}
+
+// ErrorBody describes a single error. Code is one of invalid_input,
+// not_found, rate_limited, upstream_unavailable, unauthorized, canceled or
+// internal.
+type ErrorBody struct {
+	Code    string                 `json:"code"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// statusClientClosedRequest is the non-standard status, borrowed from nginx,
+// recorded for requests whose client went away before the answer.
+const statusClientClosedRequest = 499
+
+// writeError maps a service error onto an HTTP status and the standard error
+// envelope. Anything that is not one of the util error kinds is reported as
+// an internal error without leaking its text.
+func writeError(c *gin.Context, err error) {
+	if errors.Is(err, context.Canceled) {
+		// The client has gone, so whatever failed underneath is neither our
+		// fault nor worth an error log.
+		util.JSONError(c, statusClientClosedRequest, "canceled", "request canceled", nil)
+		return
+	}
+	status, code := http.StatusInternalServerError, "internal"
+	switch {
+	case errors.Is(err, util.ErrInvalidInput):
+		status, code = http.StatusBadRequest, "invalid_input"
+	case errors.Is(err, util.ErrNotFound):
+		status, code = http.StatusNotFound, "not_found"
+	case errors.Is(err, util.ErrRateLimited):
+		status, code = http.StatusTooManyRequests, "rate_limited"
+	case errors.Is(err, util.ErrUpstreamUnavailable):
+		status, code = http.StatusServiceUnavailable, "upstream_unavailable"
+	}
+
+	msg := "internal error"
+	var details map[string]interface{}
+	var typed *util.Error
+	if errors.As(err, &typed) {
+		msg, details = typed.Message, typed.Details
+	}
+	if status == http.StatusInternalServerError {
+		util.Log(c.Request.Context()).Error("request failed", "method", c.Request.Method, "path", c.Request.URL.Path, "err", err)
+	}
+	util.JSONError(c, status, code, msg, details)
+}
+
+// invalidParam reports a bad or missing query parameter.
+func invalidParam(c *gin.Context, param, msg string) {
+	writeError(c, util.InvalidInput(msg, map[string]interface{}{"param": param}))
+}
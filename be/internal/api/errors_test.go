@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+func TestWriteErrorStatus(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tests := []struct {
+		name   string
+		err    error
+		status int
+	}{
+		{"invalid input", util.InvalidInput("bad", nil), http.StatusBadRequest},
+		{"not found", util.NotFound("no such city"), http.StatusNotFound},
+		{"rate limited", util.RateLimited("slow down", nil), http.StatusTooManyRequests},
+		{"upstream", util.UpstreamUnavailable("down", errors.New("dial")), http.StatusServiceUnavailable},
+		{"deadline", util.UpstreamUnavailable("slow", context.DeadlineExceeded), http.StatusServiceUnavailable},
+		{"canceled", context.Canceled, statusClientClosedRequest},
+		{"canceled upstream call", util.UpstreamUnavailable("down", fmt.Errorf("get: %w", context.Canceled)), statusClientClosedRequest},
+		{"other", errors.New("boom"), http.StatusInternalServerError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodGet, "/api/weather/details", nil)
+			writeError(c, tt.err)
+			if w.Code != tt.status {
+				t.Errorf("status %d, want %d", w.Code, tt.status)
+			}
+		})
+	}
+}
@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/json"
+	"math"
 	"net/http"
 	"strconv"
 	"strings"
@@ -8,7 +10,11 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/flood"
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
 	"github.com/jeffhieun/weatherdatadashboard/internal/service"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
 )
 
 // ...existing code...
@@ -17,53 +23,137 @@ import (
 type Handler struct {
 	weatherSvc *service.DefaultWeatherService
 	geocodeSvc *service.GeocodeService
+	floodSvc   *flood.Service
+	upstream   *service.UpstreamClient
 }
 
 // NewHandler constructs a new Handler with the provided services.
-func NewHandler(weatherSvc *service.DefaultWeatherService, geocodeSvc *service.GeocodeService) *Handler {
-	return &Handler{weatherSvc: weatherSvc, geocodeSvc: geocodeSvc}
+func NewHandler(weatherSvc *service.DefaultWeatherService, geocodeSvc *service.GeocodeService, floodSvc *flood.Service, upstream *service.UpstreamClient) *Handler {
+	return &Handler{weatherSvc: weatherSvc, geocodeSvc: geocodeSvc, floodSvc: floodSvc, upstream: upstream}
 }
 
 // GetWeatherData godoc
 // @Summary      Get current weather
-// @Description  Returns the current weather for a city (live fetch, caches result)
+// @Description  Returns the current weather for a city, or for exact coordinates without geocoding (live fetch, caches result)
 // @Tags         weather
-// @Param        city  query  string  true  "City name"
+// @Param        city  query  string  false  "City name (required unless lat/lon are given; used as the label for coordinate lookups)"
+// @Param        lat   query  number  false  "Latitude"
+// @Param        lon   query  number  false  "Longitude"
 // @Success      200  {object}  map[string]interface{}
-// @Failure      400  {object}  map[string]string
-// @Failure      500  {object}  map[string]string
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
 // @Router       /api/weather/current [get]
 func (h *Handler) GetWeatherDetails(c *gin.Context) {
 	city := c.Query("city")
+	latStr, lonStr := c.Query("lat"), c.Query("lon")
+	if latStr != "" || lonStr != "" {
+		lat, ok := parseCoord(latStr, 90)
+		if !ok {
+			invalidParam(c, "lat", "invalid lat")
+			return
+		}
+		lon, ok := parseCoord(lonStr, 180)
+		if !ok {
+			invalidParam(c, "lon", "invalid lon")
+			return
+		}
+		details, err := h.weatherSvc.GetWeatherByCoords(c.Request.Context(), lat, lon, city)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.JSON(200, details)
+		return
+	}
 	if city == "" {
-		c.JSON(400, gin.H{"error": "city is required"})
+		invalidParam(c, "city", "city is required")
 		return
 	}
-	details, err := h.weatherSvc.GetWeatherDetails(city)
+	details, err := h.weatherSvc.GetWeatherDetails(c.Request.Context(), city)
 	if err != nil {
-		c.JSON(500, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 	c.JSON(200, details)
 }
 
+// parseCoord parses a latitude or longitude whose magnitude is at most
+// limit. NaN and infinities are rejected.
+func parseCoord(s string, limit float64) (float64, bool) {
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || v < -limit || v > limit {
+		return 0, false
+	}
+	return v, true
+}
+
+// GetForecast godoc
+// @Summary      Get hourly and daily forecast
+// @Description  Returns the hourly series and daily aggregates for a city (cached like current conditions)
+// @Tags         weather
+// @Param        city         query  string  true   "City name"
+// @Param        days         query  int     false  "Number of forecast days (1-16, default 7)"
+// @Param        granularity  query  string  false  "hourly or daily (default both)"
+// @Success      200  {object}  model.Forecast
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      500  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/weather/forecast [get]
+func (h *Handler) GetForecast(c *gin.Context) {
+	city := c.Query("city")
+	if city == "" {
+		invalidParam(c, "city", "city is required")
+		return
+	}
+	days := 7
+	if daysStr := c.Query("days"); daysStr != "" {
+		var err error
+		days, err = strconv.Atoi(daysStr)
+		if err != nil || days < 1 || days > 16 {
+			invalidParam(c, "days", "days must be between 1 and 16")
+			return
+		}
+	}
+	granularity := c.Query("granularity")
+	if granularity != "" && granularity != "hourly" && granularity != "daily" {
+		invalidParam(c, "granularity", "granularity must be hourly or daily")
+		return
+	}
+
+	fc, err := h.weatherSvc.GetForecast(c.Request.Context(), city, days)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	switch granularity {
+	case "hourly":
+		fc.Daily = nil
+	case "daily":
+		fc.Hourly = nil
+	}
+	c.JSON(200, fc)
+}
+
 // GetCachedResult godoc
 // @Summary      Get cached weather result
 // @Description  Returns the cached/latest weather result for a city (no live fetch)
 // @Tags         weather
 // @Param        city  query  string  true  "City name"
 // @Success      200  {object}  map[string]interface{}
-// @Failure      400  {object}  map[string]string
-// @Failure      404  {object}  map[string]string
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
 // @Router       /api/weather/result [get]
 func (h *Handler) GetCachedResult(c *gin.Context) {
 	city := c.Query("city")
 	if city == "" {
-		c.JSON(400, gin.H{"error": "city is required"})
+		invalidParam(c, "city", "city is required")
 		return
 	}
 
-	if rec, ok := h.weatherSvc.GetCached(city); ok {
+	if rec, ok := h.weatherSvc.GetCached(c.Request.Context(), city); ok {
 		c.JSON(200, gin.H{
 			"city":        city,
 			"temperature": rec.Temperature,
@@ -71,7 +161,7 @@ func (h *Handler) GetCachedResult(c *gin.Context) {
 		})
 		return
 	}
-	c.JSON(404, gin.H{"error": "no cached result for city"})
+	writeError(c, util.NotFound("no cached result for city"))
 }
 
 // ListCachedResults godoc
@@ -83,60 +173,99 @@ func (h *Handler) GetCachedResult(c *gin.Context) {
 // @Param        month  query  int     false  "Month (1-12)"
 // @Param        year   query  int     false  "Year (e.g., 2025)"
 // @Success      200  {array}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
 // @Router       /api/weather/results [get]
 func (h *Handler) ListCachedResults(c *gin.Context) {
-	history := h.weatherSvc.ListAllHistory()
 	out := make([]map[string]interface{}, 0)
 
-	// Parse optional filters
-	cityFilter := c.Query("city")
-	dayStr := c.Query("day")
-	monthStr := c.Query("month")
-	yearStr := c.Query("year")
-	var day, month, year int
-	var err error
-	if dayStr != "" {
-		day, err = strconv.Atoi(dayStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid day"})
-			return
-		}
-	}
-	if monthStr != "" {
-		month, err = strconv.Atoi(monthStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid month"})
-			return
-		}
-	}
-	if yearStr != "" {
-		year, err = strconv.Atoi(yearStr)
-		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid year"})
-			return
-		}
+	f, ok := parseDateFilter(c)
+	if !ok {
+		return
 	}
+	history := h.weatherSvc.QueryHistory(c.Request.Context(), f.query())
 
 	for cityKey, list := range history {
 		for _, rec := range list {
-			// If a city filter is provided, skip non-matching entries (case-insensitive match)
-			if cityFilter != "" && strings.ToLower(rec.City) != strings.ToLower(cityFilter) {
+			if !f.matches(rec.UpdatedAt) {
 				continue
 			}
-			t := rec.UpdatedAt
-			if (day == 0 || t.Day() == day) && (month == 0 || int(t.Month()) == month) && (year == 0 || t.Year() == year) {
-				out = append(out, map[string]interface{}{
-					"city":        rec.City,
-					"temperature": rec.Temperature,
-					"fetched_at":  rec.UpdatedAt,
-					"_key":        cityKey,
-				})
+			item := map[string]interface{}{
+				"city":        rec.City,
+				"temperature": rec.Temperature,
+				"fetched_at":  rec.UpdatedAt,
+				"_key":        cityKey,
 			}
+			// Downsampled history averages several snapshots.
+			if rec.Resolution != "" {
+				item["resolution"] = rec.Resolution
+				item["samples"] = rec.Samples
+			}
+			out = append(out, item)
 		}
 	}
 	c.JSON(200, out)
 }
 
+// dateFilter holds the optional city/day/month/year filters shared by the
+// result listing endpoints.
+type dateFilter struct {
+	city             string
+	day, month, year int
+}
+
+// parseDateFilter reads the filters from the query string, writing a 400 and
+// returning false if any of them is malformed.
+func parseDateFilter(c *gin.Context) (dateFilter, bool) {
+	f := dateFilter{city: c.Query("city")}
+	for _, p := range []struct {
+		name string
+		dst  *int
+	}{{"day", &f.day}, {"month", &f.month}, {"year", &f.year}} {
+		v := c.Query(p.name)
+		if v == "" {
+			continue
+		}
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			invalidParam(c, p.name, "invalid "+p.name)
+			return dateFilter{}, false
+		}
+		*p.dst = n
+	}
+	return f, true
+}
+
+// query lets the repository narrow by city and the tightest time window the
+// filters allow; day/month without a year are still checked with matches.
+func (f dateFilter) query() store.HistoryQuery {
+	q := store.HistoryQuery{City: f.city}
+	q.From, q.To = historyWindow(f.day, f.month, f.year)
+	return q
+}
+
+func (f dateFilter) matches(t time.Time) bool {
+	return (f.day == 0 || t.Day() == f.day) && (f.month == 0 || int(t.Month()) == f.month) && (f.year == 0 || t.Year() == f.year)
+}
+
+// historyWindow converts day/month/year filters into a [from, to) range. It
+// only narrows when a year is given, since e.g. "day 5 of any month" is not a
+// contiguous range.
+func historyWindow(day, month, year int) (time.Time, time.Time) {
+	if year == 0 {
+		return time.Time{}, time.Time{}
+	}
+	if month == 0 {
+		from := time.Date(year, time.January, 1, 0, 0, 0, 0, time.Local)
+		return from, from.AddDate(1, 0, 0)
+	}
+	if day == 0 {
+		from := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.Local)
+		return from, from.AddDate(0, 1, 0)
+	}
+	from := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.Local)
+	return from, from.AddDate(0, 0, 1)
+}
+
 type CitySuggestion struct {
 	Name    string  `json:"name"`
 	Country string  `json:"country"`
@@ -160,85 +289,207 @@ var (
 // @Tags cities
 // @Param query query string true "City name (min 2 chars)"
 // @Success 200 {array} CitySuggestion
-// @Failure 400 {object} map[string]string
-// @Failure 500 {object} map[string]string
+// @Failure 400 {object} ErrorResponse
+// @Failure 404 {object} ErrorResponse
+// @Failure 500 {object} ErrorResponse
+// @Failure 503 {object} ErrorResponse
 // @Router /api/cities/search [get]
 func (h *Handler) SearchCities(c *gin.Context) {
 	query := c.Query("query")
 	if len(query) < 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query must be at least 2 characters"})
+		invalidParam(c, "query", "query must be at least 2 characters")
 		return
 	}
-	suggestions, err := h.geocodeSvc.SearchCity(query)
+	suggestions, err := h.geocodeSvc.SearchCity(c.Request.Context(), query)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		writeError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, suggestions)
 }
 
 // FloodRisk godoc
-// @Summary      Get flood risk (dynamic demo)
-// @Description  Returns a dynamic flood risk for given latitude and longitude
+// @Summary      Get flood risk
+// @Description  Returns the flood risk for given latitude and longitude, computed from past and forecast precipitation, precipitation probability and snowmelt, together with any mapped flood zones containing the point. flood_risk/probability mirror level/score for older clients.
 // @Tags         flood
-// @Param        latitude  query  string  true  "Latitude"
-// @Param        longitude query  string  true  "Longitude"
+// @Param        latitude  query  string  true   "Latitude"
+// @Param        longitude query  string  true   "Longitude"
+// @Param        city      query  string  false  "City name recorded with the stored result"
 // @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
 // @Router       /api/flood/risk [get]
 func (h *Handler) FloodRisk(c *gin.Context) {
-	latStr := c.Query("latitude")
-	lonStr := c.Query("longitude")
-	var lat, lon float64
-	var err error
-	if lat, err = strconv.ParseFloat(latStr, 64); err != nil {
-		c.JSON(400, gin.H{"error": "invalid latitude"})
+	lat, ok := parseCoord(c.Query("latitude"), 90)
+	if !ok {
+		invalidParam(c, "latitude", "invalid latitude")
 		return
 	}
-	if lon, err = strconv.ParseFloat(lonStr, 64); err != nil {
-		c.JSON(400, gin.H{"error": "invalid longitude"})
+	lon, ok := parseCoord(c.Query("longitude"), 180)
+	if !ok {
+		invalidParam(c, "longitude", "invalid longitude")
+		return
+	}
+
+	a, err := h.floodSvc.Assess(c.Request.Context(), lat, lon, c.Query("city"))
+	if err != nil {
+		writeError(c, err)
 		return
 	}
+	c.JSON(200, floodPayload(a))
+}
 
-	// Demo logic: high risk for low-lying/coastal, medium for mid, low for highland
-	risk := "low"
-	prob := 0.15
-	if lat > 8 && lat < 12 && lon > 104 && lon < 110 {
-		risk = "high"
-		prob = 0.85
-	} else if lat > 16 && lat < 22 && lon > 105 && lon < 108 {
-		risk = "medium"
-		prob = 0.55
+// FloodCurrent godoc
+// @Summary      Get current flood risk for a city
+// @Description  Geocodes the city and returns a freshly computed flood risk (recorded like /api/flood/risk)
+// @Tags         flood
+// @Param        city  query  string  true  "City name"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Failure      503  {object}  ErrorResponse
+// @Router       /api/flood/current [get]
+func (h *Handler) FloodCurrent(c *gin.Context) {
+	city := c.Query("city")
+	if city == "" {
+		invalidParam(c, "city", "city is required")
+		return
+	}
+	a, err := h.floodSvc.AssessCity(c.Request.Context(), city)
+	if err != nil {
+		writeError(c, err)
+		return
 	}
+	c.JSON(200, floodPayload(a))
+}
 
-	payload := map[string]interface{}{
-		"flood_risk":  risk,
-		"probability": prob,
-		"coords":      map[string]float64{"lat": lat, "lon": lon},
+// FloodResult godoc
+// @Summary      Get latest flood result for a city
+// @Description  Returns the most recently stored flood risk for a city (no live fetch)
+// @Tags         flood
+// @Param        city  query  string  true  "City name"
+// @Success      200  {object}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
+// @Failure      404  {object}  ErrorResponse
+// @Router       /api/flood/result [get]
+func (h *Handler) FloodResult(c *gin.Context) {
+	city := c.Query("city")
+	if city == "" {
+		invalidParam(c, "city", "city is required")
+		return
+	}
+	if a, ok := h.floodSvc.Latest(c.Request.Context(), city); ok {
+		c.JSON(200, floodPayload(a))
+		return
+	}
+	writeError(c, util.NotFound("no flood result for city"))
+}
+
+// floodPayload is the response shape shared by the flood risk endpoints;
+// flood_risk/probability/coords are kept for older clients.
+func floodPayload(a model.FloodAssessment) map[string]interface{} {
+	zones := a.Zones
+	if zones == nil {
+		zones = []model.FloodZone{}
+	}
+	return map[string]interface{}{
+		"city":        a.City,
+		"flood_risk":  a.Level,
+		"probability": a.Score,
+		"coords":      map[string]float64{"lat": a.Lat, "lon": a.Lon},
+		"score":       a.Score,
+		"level":       a.Level,
+		"factors":     a.Factors,
+		"method":      a.Method,
+		"zones":       zones,
+		"assessed_at": a.AssessedAt,
 	}
-	c.JSON(200, payload)
 }
 
 // ListFloodResults godoc
-// @Summary      List all cached flood results (stub)
-// @Description  Returns all cached flood risk records (stubbed)
+// @Summary      List stored flood results
+// @Description  Returns recorded flood risk assessments (city, coordinates, risk, probability, fetched_at) with optional filters
 // @Tags         flood
+// @Param        city   query  string  false  "City name"
+// @Param        day    query  int     false  "Day of month"
+// @Param        month  query  int     false  "Month (1-12)"
+// @Param        year   query  int     false  "Year (e.g., 2025)"
 // @Success      200  {array}  map[string]interface{}
+// @Failure      400  {object}  ErrorResponse
 // @Router       /api/flood/results [get]
 func (h *Handler) ListFloodResults(c *gin.Context) {
-	// Stub: return a static list for demonstration
-	results := []map[string]interface{}{
-		{
-			"city":        "Hanoi",
-			"risk":        "high",
-			"probability": 0.82,
-			"fetched_at":  time.Now(),
-		},
-		{
-			"city":        "Ho Chi Minh City",
-			"risk":        "medium",
-			"probability": 0.55,
-			"fetched_at":  time.Now(),
-		},
+	f, ok := parseDateFilter(c)
+	if !ok {
+		return
+	}
+	results := make([]map[string]interface{}, 0)
+	for _, rec := range h.floodSvc.ListResults(c.Request.Context(), f.query()) {
+		if !f.matches(rec.AssessedAt) {
+			continue
+		}
+		results = append(results, map[string]interface{}{
+			"city":        rec.City,
+			"lat":         rec.Lat,
+			"lon":         rec.Lon,
+			"risk":        rec.Level,
+			"probability": rec.Score,
+			"method":      rec.Method,
+			"fetched_at":  rec.AssessedAt,
+		})
 	}
 	c.JSON(200, results)
 }
+
+// FloodZoneCollection is the GeoJSON returned by /api/flood/zones
+type FloodZoneCollection struct {
+	Type     string            `json:"type" example:"FeatureCollection"`
+	Features []json.RawMessage `json:"features" swaggertype:"array,object"`
+}
+
+// FloodZones godoc
+// @Summary      List flood zones in a bounding box
+// @Description  Returns the configured flood zone polygons intersecting bbox as a GeoJSON FeatureCollection, for map overlays
+// @Tags         flood
+// @Param        bbox  query  string  true  "minLon,minLat,maxLon,maxLat"
+// @Success      200  {object}  FloodZoneCollection
+// @Failure      400  {object}  ErrorResponse
+// @Router       /api/flood/zones [get]
+func (h *Handler) FloodZones(c *gin.Context) {
+	parts := strings.Split(c.Query("bbox"), ",")
+	if len(parts) != 4 {
+		invalidParam(c, "bbox", "bbox must be minLon,minLat,maxLon,maxLat")
+		return
+	}
+	var b [4]float64
+	for i, p := range parts {
+		limit := 180.0
+		if i%2 == 1 {
+			limit = 90
+		}
+		v, ok := parseCoord(strings.TrimSpace(p), limit)
+		if !ok {
+			invalidParam(c, "bbox", "bbox must be minLon,minLat,maxLon,maxLat with longitudes in [-180,180] and latitudes in [-90,90]")
+			return
+		}
+		b[i] = v
+	}
+	if b[0] > b[2] || b[1] > b[3] {
+		invalidParam(c, "bbox", "bbox minimum must not exceed maximum")
+		return
+	}
+	features := make([]json.RawMessage, 0)
+	for _, z := range h.floodSvc.ZonesWithin(b[0], b[1], b[2], b[3]) {
+		features = append(features, z.Feature())
+	}
+	c.JSON(200, FloodZoneCollection{Type: "FeatureCollection", Features: features})
+}
+
+// UpstreamStatus godoc
+// @Summary      Upstream circuit breakers
+// @Description  Returns the circuit breaker state for every upstream host called so far
+// @Tags         admin
+// @Success      200  {array}  service.BreakerStatus
+// @Failure      401  {object}  ErrorResponse
+// @Router       /api/admin/upstreams [get]
+func (h *Handler) UpstreamStatus(c *gin.Context) {
+	c.JSON(http.StatusOK, h.upstream.BreakerStates())
+}
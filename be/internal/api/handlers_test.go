@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Invalid coordinates are rejected before any service is called, so a bare
+// Handler is enough.
+func TestCoordinateValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	h := &Handler{}
+	r := gin.New()
+	r.GET("/api/weather/current", h.GetWeatherDetails)
+	r.GET("/api/flood/risk", h.FloodRisk)
+	r.GET("/api/flood/zones", h.FloodZones)
+
+	tests := []struct {
+		name  string
+		url   string
+		param string
+	}{
+		{"weather lat above range", "/api/weather/current?lat=91&lon=105", "lat"},
+		{"weather lat NaN", "/api/weather/current?lat=NaN&lon=105", "lat"},
+		{"weather lon below range", "/api/weather/current?lat=21&lon=-181", "lon"},
+		{"weather lon infinite", "/api/weather/current?lat=21&lon=Inf", "lon"},
+		{"flood latitude missing", "/api/flood/risk?longitude=105", "latitude"},
+		{"flood latitude below range", "/api/flood/risk?latitude=-90.5&longitude=105", "latitude"},
+		{"flood latitude NaN", "/api/flood/risk?latitude=nan&longitude=105", "latitude"},
+		{"flood longitude above range", "/api/flood/risk?latitude=21&longitude=180.01", "longitude"},
+		{"flood longitude infinite", "/api/flood/risk?latitude=21&longitude=-Inf", "longitude"},
+		{"flood longitude not a number", "/api/flood/risk?latitude=21&longitude=east", "longitude"},
+		{"bbox too few values", "/api/flood/zones?bbox=105,21,106", "bbox"},
+		{"bbox huge longitudes", "/api/flood/zones?bbox=-1e18,0,1e18,1", "bbox"},
+		{"bbox latitude above range", "/api/flood/zones?bbox=105,21,106,91", "bbox"},
+		{"bbox NaN", "/api/flood/zones?bbox=NaN,21,106,22", "bbox"},
+		{"bbox infinite", "/api/flood/zones?bbox=105,-Inf,106,22", "bbox"},
+		{"bbox inverted", "/api/flood/zones?bbox=106,21,105,22", "bbox"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			assertInvalidParam(t, w, tt.param)
+		})
+	}
+}
+
+func assertInvalidParam(t *testing.T, w *httptest.ResponseRecorder, param string) {
+	t.Helper()
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status %d, want %d", w.Code, http.StatusBadRequest)
+	}
+	var body struct {
+		Error struct {
+			Details struct {
+				Param string `json:"param"`
+			} `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatal(err)
+	}
+	if body.Error.Details.Param != param {
+		t.Errorf("rejected param %q, want %q", body.Error.Details.Param, param)
+	}
+}
@@ -0,0 +1,164 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/service"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// probeTTL is how long upstream probe results are reused, so repeated
+// diagnostics calls do not send a request to every upstream each time.
+const probeTTL = 10 * time.Second
+
+// HealthHandler serves the liveness, readiness and diagnostics endpoints.
+type HealthHandler struct {
+	repo      store.WeatherRepository
+	upstream  *service.UpstreamClient
+	probeURLs []string
+	version   string
+	started   time.Time
+	// now reads the clock for the probe cache.
+	now func() time.Time
+
+	probeMu  sync.Mutex
+	probedAt time.Time
+	probes   []service.ProbeResult
+}
+
+// NewHealthHandler builds the handler; probeURLs are the upstream endpoints
+// checked by the diagnostics endpoint.
+func NewHealthHandler(repo store.WeatherRepository, upstream *service.UpstreamClient, probeURLs []string, version string) *HealthHandler {
+	return &HealthHandler{repo: repo, upstream: upstream, probeURLs: probeURLs, version: version, started: time.Now(), now: time.Now}
+}
+
+// HealthStatus is the body of /healthz and /readyz.
+type HealthStatus struct {
+	Status string `json:"status" example:"ok"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Diagnostics is the body of /api/admin/diagnostics.
+type Diagnostics struct {
+	Version       string                  `json:"version"`
+	Revision      string                  `json:"revision,omitempty"`
+	GoVersion     string                  `json:"goVersion"`
+	StartedAt     time.Time               `json:"startedAt"`
+	UptimeSeconds int64                   `json:"uptimeSeconds"`
+	Repository    RepositoryDiagnostics   `json:"repository"`
+	Upstreams     []service.ProbeResult   `json:"upstreams"`
+	Breakers      []service.BreakerStatus `json:"breakers"`
+}
+
+// RepositoryDiagnostics reports repository reachability and sizes.
+type RepositoryDiagnostics struct {
+	Reachable bool        `json:"reachable"`
+	Error     string      `json:"error,omitempty"`
+	Stats     store.Stats `json:"stats"`
+}
+
+// Healthz godoc
+// @Summary      Liveness probe
+// @Description  Returns 200 while the process is serving requests
+// @Tags         health
+// @Success      200  {object}  HealthStatus
+// @Router       /healthz [get]
+func (h *HealthHandler) Healthz(c *gin.Context) {
+	c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Readyz godoc
+// @Summary      Readiness probe
+// @Description  Returns 200 when the repository (memory, on-disk or Redis) is reachable, 503 otherwise
+// @Tags         health
+// @Success      200  {object}  HealthStatus
+// @Failure      503  {object}  HealthStatus
+// @Router       /readyz [get]
+func (h *HealthHandler) Readyz(c *gin.Context) {
+	// The cause is logged rather than returned: it can name internal hosts.
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		util.Log(c.Request.Context()).Warn("readiness check failed", "err", err)
+		c.JSON(http.StatusServiceUnavailable, HealthStatus{Status: "unavailable", Error: "repository unreachable"})
+		return
+	}
+	c.JSON(http.StatusOK, HealthStatus{Status: "ok"})
+}
+
+// Diagnostics godoc
+// @Summary      Dependency diagnostics
+// @Description  Reports build version, uptime, repository reachability and sizes, upstream reachability and circuit breaker states. Upstream probes are reused for 10 seconds.
+// @Tags         admin
+// @Success      200  {object}  Diagnostics
+// @Failure      401  {object}  ErrorResponse
+// @Router       /api/admin/diagnostics [get]
+func (h *HealthHandler) Diagnostics(c *gin.Context) {
+	d := Diagnostics{
+		Version:       h.version,
+		Revision:      vcsRevision(),
+		GoVersion:     runtime.Version(),
+		StartedAt:     h.started,
+		UptimeSeconds: int64(time.Since(h.started).Seconds()),
+		Upstreams:     h.probeUpstreams(c.Request.Context()),
+		Breakers:      h.upstream.BreakerStates(),
+	}
+	if err := h.repo.Ping(c.Request.Context()); err != nil {
+		util.Log(c.Request.Context()).Warn("diagnostics: repository ping failed", "err", err)
+		d.Repository.Error = "ping failed"
+	} else {
+		d.Repository.Reachable = true
+		d.Repository.Stats = h.repo.Stats(c.Request.Context())
+	}
+	c.JSON(http.StatusOK, d)
+}
+
+// probeUpstreams returns the upstream probe results, probing again only once
+// the previous round is older than probeTTL. Concurrent callers share one
+// round. Probe errors are logged and reported only as "unreachable".
+func (h *HealthHandler) probeUpstreams(ctx context.Context) []service.ProbeResult {
+	h.probeMu.Lock()
+	defer h.probeMu.Unlock()
+	if h.probes != nil && h.now().Sub(h.probedAt) < probeTTL {
+		return h.probes
+	}
+	// The results are shared, so they must not fail because the caller that
+	// happened to run them went away; Probe bounds each one with a timeout.
+	ctx = context.WithoutCancel(ctx)
+	results := make([]service.ProbeResult, len(h.probeURLs))
+	var wg sync.WaitGroup
+	for i, u := range h.probeURLs {
+		wg.Add(1)
+		go func(i int, u string) {
+			defer wg.Done()
+			res := h.upstream.Probe(ctx, u)
+			if res.Error != "" {
+				util.Log(ctx).Warn("diagnostics: upstream probe failed", "url", u, "err", res.Error)
+				res.Error = "unreachable"
+			}
+			results[i] = res
+		}(i, u)
+	}
+	wg.Wait()
+	h.probes, h.probedAt = results, h.now()
+	return results
+}
+
+// vcsRevision returns the commit the binary was built from, if recorded.
+func vcsRevision() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	for _, s := range info.Settings {
+		if s.Key == "vcs.revision" {
+			return s.Value
+		}
+	}
+	return ""
+}
@@ -0,0 +1,137 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/service"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+)
+
+// downRepository fails every ping with an error naming an internal host.
+type downRepository struct {
+	store.WeatherRepository
+}
+
+func (downRepository) Ping(ctx context.Context) error {
+	return errors.New("dial tcp redis.internal:6379: connection refused")
+}
+
+func newHealthRouter(h *HealthHandler, token string) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.GET("/readyz", h.Readyz)
+	r.Group("/api/admin", AdminAuth(token)).GET("/diagnostics", h.Diagnostics)
+	return r
+}
+
+func serve(r http.Handler, url, auth string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestHealthHidesErrors(t *testing.T) {
+	h := NewHealthHandler(downRepository{store.NewInMemoryRepository()}, service.NewUpstreamClient(nil, service.UpstreamOptions{}),
+		[]string{"http://127.0.0.1:1/unreachable"}, "test")
+	r := newHealthRouter(h, "")
+
+	w := serve(r, "/readyz", "")
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("readyz status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if strings.Contains(w.Body.String(), "redis.internal") {
+		t.Errorf("readyz leaked the error: %s", w.Body)
+	}
+
+	w = serve(r, "/api/admin/diagnostics", "")
+	var d Diagnostics
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.Repository.Reachable || d.Repository.Error != "ping failed" {
+		t.Errorf("repository diagnostics %+v, want a generic failure", d.Repository)
+	}
+	if len(d.Upstreams) != 1 || d.Upstreams[0].Reachable || d.Upstreams[0].Error != "unreachable" {
+		t.Errorf("upstream diagnostics %+v, want a generic failure", d.Upstreams)
+	}
+	if strings.Contains(w.Body.String(), "redis.internal") || strings.Contains(w.Body.String(), "connection refused") {
+		t.Errorf("diagnostics leaked an error: %s", w.Body)
+	}
+}
+
+func TestDiagnosticsReusesProbes(t *testing.T) {
+	var hits int32
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&hits, 1)
+	}))
+	defer upstream.Close()
+	h := NewHealthHandler(store.NewInMemoryRepository(), service.NewUpstreamClient(upstream.Client(), service.UpstreamOptions{}),
+		[]string{upstream.URL + "/a", upstream.URL + "/b"}, "test")
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h.now = func() time.Time { return now }
+	r := newHealthRouter(h, "")
+
+	for i := 0; i < 3; i++ {
+		serve(r, "/api/admin/diagnostics", "")
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("upstreams probed %d times, want 2 for one round", n)
+	}
+	now = now.Add(probeTTL)
+	w := serve(r, "/api/admin/diagnostics", "")
+	if n := atomic.LoadInt32(&hits); n != 4 {
+		t.Errorf("upstreams probed %d times, want a second round once the results expired", n)
+	}
+	var d Diagnostics
+	if err := json.Unmarshal(w.Body.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Upstreams) != 2 || !d.Upstreams[0].Reachable || !d.Upstreams[1].Reachable {
+		t.Errorf("upstreams %+v, want both reachable", d.Upstreams)
+	}
+}
+
+func TestAdminAuth(t *testing.T) {
+	h := NewHealthHandler(store.NewInMemoryRepository(), service.NewUpstreamClient(nil, service.UpstreamOptions{}), nil, "test")
+	tests := []struct {
+		name   string
+		token  string
+		auth   string
+		status int
+	}{
+		{"open without a token", "", "", http.StatusOK},
+		{"missing header", "s3cret", "", http.StatusUnauthorized},
+		{"wrong token", "s3cret", "Bearer guess", http.StatusUnauthorized},
+		{"not a bearer", "s3cret", "s3cret", http.StatusUnauthorized},
+		{"right token", "s3cret", "Bearer s3cret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newHealthRouter(h, tt.token)
+			w := serve(r, "/api/admin/diagnostics", tt.auth)
+			if w.Code != tt.status {
+				t.Fatalf("status %d, want %d", w.Code, tt.status)
+			}
+			if w.Code == http.StatusUnauthorized && !strings.Contains(w.Body.String(), `"code":"unauthorized"`) {
+				t.Errorf("body %s, want the unauthorized error", w.Body)
+			}
+			// Readiness stays open for load balancers.
+			if w := serve(r, "/readyz", ""); w.Code != http.StatusOK {
+				t.Errorf("readyz status %d", w.Code)
+			}
+		})
+	}
+}
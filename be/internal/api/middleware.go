@@ -0,0 +1,98 @@
+package api
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// RequestIDHeader carries the request ID in both directions.
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLen bounds an incoming ID so clients cannot bloat the logs.
+const maxRequestIDLen = 128
+
+// RequestID propagates the caller's X-Request-ID, or generates one, echoes
+// it in the response and stores it in the request context, whose logger
+// then tags every entry with it.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if !validRequestID(id) {
+			id = newRequestID()
+		}
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(util.WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+// AccessLog writes one structured entry per request, replacing gin's text
+// logger: warn for 4xx, error for 5xx, info otherwise.
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		status := c.Writer.Status()
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		util.Log(c.Request.Context()).Log(c.Request.Context(), level, "request",
+			"method", c.Request.Method,
+			"route", c.FullPath(),
+			"path", c.Request.URL.Path,
+			"query", c.Request.URL.RawQuery,
+			"status", status,
+			"bytes", c.Writer.Size(),
+			"client_ip", c.ClientIP(),
+			"duration_ms", time.Since(start).Milliseconds(),
+		)
+	}
+}
+
+// AdminAuth requires "Authorization: Bearer <token>" on the routes it
+// guards. An empty token leaves them open.
+func AdminAuth(token string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token == "" {
+			c.Next()
+			return
+		}
+		got, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", "Bearer")
+			util.JSONError(c, http.StatusUnauthorized, "unauthorized", "admin token required", nil)
+			return
+		}
+		c.Next()
+	}
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLen {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < 0x21 || id[i] > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,170 @@
+// Package cassette records upstream HTTP interactions to disk and replays
+// them, so code that talks to Open-Meteo can run deterministically without
+// network access.
+package cassette
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Mode selects what a Recorder does with each request.
+type Mode string
+
+const (
+	// ModeRecord forwards requests upstream and saves every response.
+	ModeRecord Mode = "record"
+	// ModeReplay answers from the cassette and never touches the network.
+	ModeReplay Mode = "replay"
+)
+
+// ErrNotRecorded is returned in replay mode for a request with no saved
+// interaction.
+var ErrNotRecorded = errors.New("cassette: interaction not recorded")
+
+// Interaction is one saved request/response pair; each is stored as its own
+// JSON file so cassettes diff cleanly and can be edited by hand.
+type Interaction struct {
+	Request    RecordedRequest  `json:"request"`
+	Response   RecordedResponse `json:"response"`
+	RecordedAt time.Time        `json:"recordedAt"`
+}
+
+type RecordedRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type RecordedResponse struct {
+	StatusCode int         `json:"statusCode"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// Recorder is an http.RoundTripper that records to or replays from a
+// cassette directory.
+type Recorder struct {
+	mode Mode
+	dir  string
+	next http.RoundTripper
+}
+
+// New returns a Recorder over dir. next is the transport used in record mode
+// and may be nil for http.DefaultTransport.
+func New(mode Mode, dir string, next http.RoundTripper) (*Recorder, error) {
+	if dir == "" {
+		return nil, fmt.Errorf("cassette: directory is required")
+	}
+	switch mode {
+	case ModeRecord:
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return nil, fmt.Errorf("cassette: %w", err)
+		}
+	case ModeReplay:
+		if _, err := os.Stat(dir); err != nil {
+			return nil, fmt.Errorf("cassette: %w", err)
+		}
+	default:
+		return nil, fmt.Errorf("cassette: unknown mode %q (want record or replay)", mode)
+	}
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &Recorder{mode: mode, dir: dir, next: next}, nil
+}
+
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	path := filepath.Join(r.dir, Key(req.Method, req.URL.String())+".json")
+	if r.mode == ModeReplay {
+		return r.replay(req, path)
+	}
+	resp, err := r.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	in := Interaction{
+		Request:    RecordedRequest{Method: req.Method, URL: req.URL.String()},
+		Response:   RecordedResponse{StatusCode: resp.StatusCode, Header: resp.Header, Body: string(body)},
+		RecordedAt: time.Now().UTC(),
+	}
+	if err := save(path, in); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (r *Recorder) replay(req *http.Request, path string) (*http.Response, error) {
+	raw, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s %s", ErrNotRecorded, req.Method, req.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var in Interaction
+	if err := json.Unmarshal(raw, &in); err != nil {
+		return nil, fmt.Errorf("cassette: invalid interaction %s: %w", path, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        in.Response.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// Key names the interaction for a request. Query parameters are sorted so
+// the key does not depend on the order they were added in.
+func Key(method, rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil {
+		u.RawQuery = u.Query().Encode()
+		rawURL = u.String()
+	}
+	sum := sha256.Sum256([]byte(method + " " + rawURL))
+	return hex.EncodeToString(sum[:8])
+}
+
+// save writes the interaction atomically so concurrent recordings of the
+// same request never leave a torn file.
+func save(path string, in Interaction) error {
+	raw, err := json.MarshalIndent(in, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".interaction-*")
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if _, err := tmp.Write(append(raw, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,158 @@
+package cassette_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/cassette"
+	"github.com/jeffhieun/weatherdatadashboard/internal/service"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// newUpstream answers /ok with a JSON body and /down with a 503, counting
+// the requests it sees.
+func newUpstream(t *testing.T, hits *int32) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(hits, 1)
+		if r.URL.Path == "/down" {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		io.WriteString(w, `{"temperature":21.5}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestRecordThenReplay(t *testing.T) {
+	var hits int32
+	srv := newUpstream(t, &hits)
+	dir := filepath.Join(t.TempDir(), "cassettes")
+	rec, err := cassette.New(cassette.ModeRecord, dir, srv.Client().Transport)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordURL := srv.URL + "/ok?longitude=105.85&latitude=21.03"
+
+	// The caller still gets the body after it has been saved.
+	resp, body := get(t, &http.Client{Transport: rec}, recordURL)
+	if resp.StatusCode != http.StatusOK || body != `{"temperature":21.5}` {
+		t.Fatalf("recording got %d %q", resp.StatusCode, body)
+	}
+	get(t, &http.Client{Transport: rec}, srv.URL+"/down")
+
+	// One file per interaction, named after its key, and no temporary files.
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("cassette holds %d files, want 2", len(entries))
+	}
+	raw, err := os.ReadFile(filepath.Join(dir, cassette.Key(http.MethodGet, recordURL)+".json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var in cassette.Interaction
+	if err := json.Unmarshal(raw, &in); err != nil {
+		t.Fatal(err)
+	}
+	if in.Request.URL != recordURL || in.Response.StatusCode != http.StatusOK || in.Response.Body != body || in.RecordedAt.IsZero() {
+		t.Errorf("saved interaction %+v", in)
+	}
+
+	// Replay never reaches the upstream, and the query order does not matter.
+	srv.Close()
+	replay, err := cassette.New(cassette.ModeReplay, dir, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replay}
+	resp, replayed := get(t, client, srv.URL+"/ok?latitude=21.03&longitude=105.85")
+	if resp.StatusCode != http.StatusOK || replayed != body {
+		t.Errorf("replay got %d %q, want %q", resp.StatusCode, replayed, body)
+	}
+	if got := resp.Header.Get("X-Query"); got != "longitude=105.85&latitude=21.03" {
+		t.Errorf("replayed header %q, want the recorded one", got)
+	}
+	if resp, _ := get(t, client, srv.URL+"/down"); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("replayed status %d, want the recorded 503", resp.StatusCode)
+	}
+	if n := atomic.LoadInt32(&hits); n != 2 {
+		t.Errorf("upstream hit %d times, want 2 while recording only", n)
+	}
+}
+
+func TestKey(t *testing.T) {
+	base := cassette.Key(http.MethodGet, "https://api.open-meteo.com/v1/forecast?b=2&a=1")
+	if got := cassette.Key(http.MethodGet, "https://api.open-meteo.com/v1/forecast?a=1&b=2"); got != base {
+		t.Errorf("reordered query gave key %s, want %s", got, base)
+	}
+	for _, other := range []struct{ method, url string }{
+		{http.MethodPost, "https://api.open-meteo.com/v1/forecast?a=1&b=2"},
+		{http.MethodGet, "https://api.open-meteo.com/v1/forecast?a=1&b=3"},
+		{http.MethodGet, "https://api.open-meteo.com/v1/search?a=1&b=2"},
+	} {
+		if cassette.Key(other.method, other.url) == base {
+			t.Errorf("%s %s shares key %s", other.method, other.url, base)
+		}
+	}
+	if len(base) != 16 {
+		t.Errorf("key %q, want 16 hex digits", base)
+	}
+}
+
+func TestReplayMiss(t *testing.T) {
+	replay, err := cassette.New(cassette.ModeReplay, t.TempDir(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &http.Client{Transport: replay}
+	if _, err := client.Get("https://api.open-meteo.com/v1/forecast?latitude=1"); !errors.Is(err, cassette.ErrNotRecorded) {
+		t.Errorf("Get = %v, want %v", err, cassette.ErrNotRecorded)
+	}
+
+	var out map[string]interface{}
+	err = service.NewUpstreamClient(client, service.UpstreamOptions{}).GetJSON(context.Background(), "https://api.open-meteo.com/v1/forecast?latitude=1", &out)
+	if !errors.Is(err, util.ErrUpstreamUnavailable) || !errors.Is(err, cassette.ErrNotRecorded) {
+		t.Errorf("GetJSON = %v, want an unavailable upstream caused by the miss", err)
+	}
+}
+
+func TestNewRejectsBadSetup(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := cassette.New("rewind", dir, nil); err == nil {
+		t.Error("unknown mode accepted")
+	}
+	if _, err := cassette.New(cassette.ModeRecord, "", nil); err == nil {
+		t.Error("empty directory accepted")
+	}
+	if _, err := cassette.New(cassette.ModeReplay, filepath.Join(dir, "missing"), nil); err == nil {
+		t.Error("replay from a missing directory accepted")
+	}
+}
@@ -3,23 +3,97 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
-	WeatherAPIURL string
-	GeocodeAPIURL string
-	CacheTTL      int
-	Port          string
-	RedisURL      string
+	WeatherAPIURL  string
+	GeocodeAPIURL  string
+	CacheTTL       int
+	CacheStaleTTL  int
+	RefreshWorkers int
+	Port           string
+	RedisURL       string
+	StorePath      string
+	FloodZonesPath string
+	FloodAPIURL    string
+	LogLevel       string
+	LogFormat      string
+	// HTTP server timeouts and shutdown deadline, in seconds
+	ReadTimeout       int
+	ReadHeaderTimeout int
+	WriteTimeout      int
+	IdleTimeout       int
+	ShutdownTimeout   int
+	// Upstream HTTP resilience
+	UpstreamTimeout  int
+	UpstreamRetries  int
+	BreakerThreshold int
+	BreakerCooldown  int
+	// History retention, in seconds; zero disables a step
+	HistoryRawAge         int
+	HistoryHourlyAge      int
+	HistoryMaxAge         int
+	HistoryMaxPerCity     int
+	HistoryCompactSeconds int
+	// Flood result retention, in seconds and per city; applied by the
+	// history compaction job
+	FloodResultsMaxAge     int
+	FloodResultsMaxPerCity int
+	// Cities whose latest weather is exported as metrics
+	MetricsCities []string
+	// Record/replay of upstream responses
+	CassetteMode string
+	CassetteDir  string
+	// OTLP endpoint traces are exported to; tracing is off when empty
+	OTLPEndpoint string
+	// Bearer token required by /api/admin/*; the routes are open when empty
+	AdminToken string
 }
 
 func Load() Config {
 	return Config{
-		WeatherAPIURL: getenv("WEATHER_API_URL", "https://api.open-meteo.com/v1/forecast"),
-		GeocodeAPIURL: getenv("GEOCODE_API_URL", "https://geocoding-api.open-meteo.com/v1/search"),
-		CacheTTL:      getenvInt("CACHE_TTL", 300),
-		Port:          getenv("PORT", "8080"),
-		RedisURL:      getenv("REDIS_URL", ""),
+		WeatherAPIURL:  getenv("WEATHER_API_URL", "https://api.open-meteo.com/v1/forecast"),
+		GeocodeAPIURL:  getenv("GEOCODE_API_URL", "https://geocoding-api.open-meteo.com/v1/search"),
+		CacheTTL:       getenvInt("CACHE_TTL", 300),
+		CacheStaleTTL:  getenvInt("CACHE_STALE_TTL", 0),
+		RefreshWorkers: getenvInt("REFRESH_WORKERS", 4),
+		Port:           getenv("PORT", "8080"),
+		RedisURL:       getenv("REDIS_URL", ""),
+		StorePath:      getenv("STORE_PATH", ""),
+		FloodZonesPath: getenv("FLOOD_ZONES_PATH", ""),
+		FloodAPIURL:    getenv("FLOOD_API_URL", ""),
+		LogLevel:       getenv("LOG_LEVEL", "info"),
+		LogFormat:      getenv("LOG_FORMAT", "json"),
+
+		ReadTimeout:       getenvInt("HTTP_READ_TIMEOUT", 15),
+		ReadHeaderTimeout: getenvInt("HTTP_READ_HEADER_TIMEOUT", 5),
+		WriteTimeout:      getenvInt("HTTP_WRITE_TIMEOUT", 60),
+		IdleTimeout:       getenvInt("HTTP_IDLE_TIMEOUT", 120),
+		ShutdownTimeout:   getenvInt("SHUTDOWN_TIMEOUT", 20),
+
+		UpstreamTimeout:  getenvInt("UPSTREAM_TIMEOUT", 10),
+		UpstreamRetries:  getenvInt("UPSTREAM_RETRIES", 2),
+		BreakerThreshold: getenvInt("BREAKER_THRESHOLD", 5),
+		BreakerCooldown:  getenvInt("BREAKER_COOLDOWN", 30),
+
+		HistoryRawAge:         getenvInt("HISTORY_RAW_AGE", 86400),
+		HistoryHourlyAge:      getenvInt("HISTORY_HOURLY_AGE", 7*86400),
+		HistoryMaxAge:         getenvInt("HISTORY_MAX_AGE", 90*86400),
+		HistoryMaxPerCity:     getenvInt("HISTORY_MAX_PER_CITY", 2000),
+		HistoryCompactSeconds: getenvInt("HISTORY_COMPACT_INTERVAL", 3600),
+
+		FloodResultsMaxAge:     getenvInt("FLOOD_RESULTS_MAX_AGE", 30*86400),
+		FloodResultsMaxPerCity: getenvInt("FLOOD_RESULTS_MAX_PER_CITY", 500),
+
+		MetricsCities: getenvList("METRICS_CITIES"),
+
+		CassetteMode: getenv("UPSTREAM_CASSETTE_MODE", ""),
+		CassetteDir:  getenv("UPSTREAM_CASSETTE_DIR", "testdata/cassettes"),
+
+		OTLPEndpoint: getenv("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", getenv("OTEL_EXPORTER_OTLP_ENDPOINT", "")),
+
+		AdminToken: getenv("ADMIN_TOKEN", ""),
 	}
 }
 
@@ -38,3 +112,14 @@ func getenvInt(key string, fallback int) int {
 	}
 	return fallback
 }
+
+// getenvList splits a comma-separated variable, dropping empty entries.
+func getenvList(key string) []string {
+	var out []string
+	for _, v := range strings.Split(os.Getenv(key), ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
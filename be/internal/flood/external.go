@@ -0,0 +1,53 @@
+package flood
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// RiskAPI is an external flood risk service answering with a level (low,
+// medium or high) and a probability in [0,1] for a point.
+type RiskAPI interface {
+	Risk(ctx context.Context, lat, lon float64) (level string, probability float64, err error)
+}
+
+// UseRiskAPI makes Assess prefer api's answer to the precipitation model,
+// which is used only when api fails.
+func (s *Service) UseRiskAPI(api RiskAPI) {
+	s.riskAPI = api
+}
+
+// assessExternal asks the risk API, if one is configured, and reports
+// whether it gave a usable answer.
+func (s *Service) assessExternal(ctx context.Context, lat, lon float64) (model.FloodAssessment, bool) {
+	if s.riskAPI == nil {
+		return model.FloodAssessment{}, false
+	}
+	level, prob, err := s.riskAPI.Risk(ctx, lat, lon)
+	level = strings.ToLower(level)
+	switch {
+	case err != nil:
+	case level != LevelLow && level != LevelMedium && level != LevelHigh:
+		err = fmt.Errorf("unknown risk level %q", level)
+	case !(prob >= 0 && prob <= 1):
+		err = fmt.Errorf("probability %v outside [0,1]", prob)
+	}
+	if err != nil {
+		util.Log(ctx).Warn("flood: risk API unavailable, using precipitation data", "lat", lat, "lon", lon, "err", err)
+		return model.FloodAssessment{}, false
+	}
+	return model.FloodAssessment{
+		Lat:        lat,
+		Lon:        lon,
+		Score:      round(prob, 3),
+		Level:      level,
+		Factors:    []model.FloodFactor{},
+		Method:     MethodExternal,
+		AssessedAt: time.Now(),
+	}, true
+}
@@ -0,0 +1,50 @@
+package flood
+
+import (
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+// fallbackScore is the score reported for each level by the fallback.
+var fallbackScore = map[string]float64{
+	LevelLow:    0.15,
+	LevelMedium: 0.55,
+	LevelHigh:   0.85,
+}
+
+// fallbackAssessment is used when precipitation data is unavailable. The
+// highest recognised risk class among the containing zones wins; without one
+// the original demo regions apply.
+func fallbackAssessment(lat, lon float64, zones []*Zone) model.FloodAssessment {
+	risk := ""
+	for _, z := range zones {
+		if s, ok := fallbackScore[z.RiskClass]; ok && s > fallbackScore[risk] {
+			risk = z.RiskClass
+		}
+	}
+	if risk == "" {
+		risk = regionalLevel(lat, lon)
+	}
+	return model.FloodAssessment{
+		Lat:        lat,
+		Lon:        lon,
+		Score:      fallbackScore[risk],
+		Level:      risk,
+		Factors:    []model.FloodFactor{},
+		Method:     MethodFallback,
+		AssessedAt: time.Now(),
+	}
+}
+
+// regionalLevel is the original demo logic: high risk for the low-lying
+// Mekong delta and southern coast, medium for the Red River delta, low
+// elsewhere.
+func regionalLevel(lat, lon float64) string {
+	if lat > 8 && lat < 12 && lon > 104 && lon < 110 {
+		return LevelHigh
+	} else if lat > 16 && lat < 22 && lon > 105 && lon < 108 {
+		return LevelMedium
+	}
+	return LevelLow
+}
@@ -0,0 +1,213 @@
+// Package flood estimates flood risk from recent and forecast precipitation.
+package flood
+
+import (
+	"context"
+	"math"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Risk levels
+const (
+	LevelLow    = "low"
+	LevelMedium = "medium"
+	LevelHigh   = "high"
+)
+
+// Assessment methods
+const (
+	MethodPrecipitation = "precipitation"
+	MethodFallback      = "fallback"
+	MethodExternal      = "external"
+)
+
+const (
+	pastWindow     = 72 * time.Hour
+	forecastWindow = 48 * time.Hour
+
+	// Each factor is normalized to [0,1] against the amount at which it alone
+	// saturates, then weighted; the weights sum to 1.
+	pastRainSaturation     = 100.0 // mm over the past window
+	forecastRainSaturation = 80.0  // mm over the forecast window
+	snowmeltSaturation     = 40.0  // mm water equivalent over both windows
+
+	pastRainWeight     = 0.35
+	forecastRainWeight = 0.30
+	precipProbWeight   = 0.15
+	snowmeltWeight     = 0.20
+
+	// Water equivalent of one metre of melted snow, assuming settled snow
+	// at ~300 kg/m³.
+	snowWaterPerMetre = 300.0 // mm
+
+	mediumThreshold = 0.3
+	highThreshold   = 0.6
+)
+
+// Source resolves city names and supplies the hourly precipitation series
+// the model works from. service.WeatherProvider satisfies it.
+type Source interface {
+	Geocode(ctx context.Context, city string) (model.City, error)
+	Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (model.Forecast, error)
+}
+
+// Service computes flood assessments from a Source and records each one in
+// the repository. When flood zone polygons are loaded, each assessment lists
+// the zones containing the point.
+type Service struct {
+	source  Source
+	repo    store.WeatherRepository
+	zones   *ZoneIndex
+	riskAPI RiskAPI
+}
+
+// NewService builds a Service; zones may be nil when no zone map is
+// configured.
+func NewService(source Source, repo store.WeatherRepository, zones *ZoneIndex) *Service {
+	if zones == nil {
+		zones = NewZoneIndex(nil)
+	}
+	return &Service{source: source, repo: repo, zones: zones}
+}
+
+// Assess scores flood risk at the coordinates from accumulated rain over the
+// past three days, forecast rain and its probability over the next two, and
+// snowmelt, unless a risk API is configured and answers. If the
+// precipitation data cannot be fetched the fallback is returned instead,
+// based on the risk class of the containing zones or, with none, the coarse
+// regional boxes. city labels the stored result and may be empty. If ctx
+// ends first, nothing is stored and ctx's error is returned.
+func (s *Service) Assess(ctx context.Context, lat, lon float64, city string) (_ model.FloodAssessment, err error) {
+	ctx, span := tracing.Start(ctx, "flood.assess",
+		tracing.AttrCity.String(city), attribute.Float64("lat", lat), attribute.Float64("lon", lon))
+	defer func() { tracing.End(span, err) }()
+	zones := s.zones.Containing(lat, lon)
+	a, ok := s.assessExternal(ctx, lat, lon)
+	if !ok {
+		var fc model.Forecast
+		fc, err = s.source.Forecast(ctx, lat, lon, int(pastWindow/(24*time.Hour)), int(forecastWindow/(24*time.Hour))+1)
+		// A caller that has gone gets no fallback, and nothing is recorded
+		// for it.
+		if ctx.Err() != nil {
+			return model.FloodAssessment{}, ctx.Err()
+		}
+		if err != nil || len(fc.Hourly) == 0 {
+			util.Log(ctx).Warn("flood: precipitation data unavailable, using fallback", "city", city, "lat", lat, "lon", lon, "err", err)
+			a = fallbackAssessment(lat, lon, zones)
+		} else {
+			a = assess(lat, lon, fc.Hourly, time.Now())
+		}
+	}
+	a.City = city
+	for _, z := range zones {
+		a.Zones = append(a.Zones, z.ref())
+	}
+	span.SetAttributes(
+		attribute.String("flood.level", a.Level),
+		attribute.Float64("flood.score", a.Score),
+		attribute.String("flood.method", a.Method),
+		attribute.Int("flood.zones", len(a.Zones)),
+	)
+	s.repo.AppendFloodResult(context.WithoutCancel(ctx), a)
+	return a, nil
+}
+
+// AssessCity geocodes city and assesses flood risk at its coordinates.
+func (s *Service) AssessCity(ctx context.Context, city string) (model.FloodAssessment, error) {
+	ctx = util.WithLogAttrs(ctx, "city", city)
+	loc, err := s.source.Geocode(ctx, city)
+	if err != nil {
+		return model.FloodAssessment{}, err
+	}
+	return s.Assess(ctx, loc.Lat, loc.Lon, loc.Name)
+}
+
+// Latest returns the most recent stored assessment for city, if any.
+func (s *Service) Latest(ctx context.Context, city string) (model.FloodAssessment, bool) {
+	return s.repo.LatestFloodResult(ctx, city)
+}
+
+// ListResults returns stored assessments matching q, oldest first.
+func (s *Service) ListResults(ctx context.Context, q store.HistoryQuery) []model.FloodAssessment {
+	return s.repo.QueryFloodResults(ctx, q)
+}
+
+// ZonesWithin returns the loaded zones intersecting the bounding box.
+func (s *Service) ZonesWithin(minLon, minLat, maxLon, maxLat float64) []*Zone {
+	return s.zones.Within(minLon, minLat, maxLon, maxLat)
+}
+
+func assess(lat, lon float64, hourly []model.HourlyForecast, now time.Time) model.FloodAssessment {
+	var pastRain, forecastRain, maxProb, melt float64
+	from, to := now.Add(-pastWindow), now.Add(forecastWindow)
+	for i, h := range hourly {
+		if h.Time.Before(from) || !h.Time.Before(to) {
+			continue
+		}
+		if h.Time.After(now) {
+			forecastRain += h.Precipitation
+			maxProb = math.Max(maxProb, h.PrecipProb)
+		} else {
+			pastRain += h.Precipitation
+		}
+		// Snow depth that disappears while it is above freezing is melt.
+		if i > 0 && h.Temperature > 0 {
+			if drop := hourly[i-1].SnowDepth - h.SnowDepth; drop > 0 {
+				melt += drop * snowWaterPerMetre
+			}
+		}
+	}
+
+	factors := []model.FloodFactor{
+		factor("past_rain_72h", pastRain, "mm", pastRainWeight, pastRain/pastRainSaturation),
+		factor("forecast_rain_48h", forecastRain, "mm", forecastRainWeight, forecastRain/forecastRainSaturation),
+		factor("precip_probability_max", maxProb, "ratio", precipProbWeight, maxProb),
+		factor("snowmelt", melt, "mm", snowmeltWeight, melt/snowmeltSaturation),
+	}
+	score := 0.0
+	for _, f := range factors {
+		score += f.Contribution
+	}
+	score = round(score, 3)
+	return model.FloodAssessment{
+		Lat:        lat,
+		Lon:        lon,
+		Score:      score,
+		Level:      levelFor(score),
+		Factors:    factors,
+		Method:     MethodPrecipitation,
+		AssessedAt: now,
+	}
+}
+
+func factor(name string, value float64, unit string, weight, normalized float64) model.FloodFactor {
+	normalized = math.Max(0, math.Min(1, normalized))
+	return model.FloodFactor{
+		Name:         name,
+		Value:        round(value, 2),
+		Unit:         unit,
+		Contribution: round(weight*normalized, 3),
+	}
+}
+
+func levelFor(score float64) string {
+	switch {
+	case score >= highThreshold:
+		return LevelHigh
+	case score >= mediumThreshold:
+		return LevelMedium
+	default:
+		return LevelLow
+	}
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
@@ -0,0 +1,225 @@
+package flood
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+)
+
+// series builds hourly entries from 80 hours before now to 60 hours after,
+// at 10°C without snow, and lets set adjust the entry at each hour offset.
+func series(now time.Time, set func(offset int, h *model.HourlyForecast)) []model.HourlyForecast {
+	var out []model.HourlyForecast
+	for offset := -80; offset <= 60; offset++ {
+		h := model.HourlyForecast{Time: now.Add(time.Duration(offset) * time.Hour), Temperature: 10}
+		if set != nil {
+			set(offset, &h)
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+func TestAssess(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		set   func(offset int, h *model.HourlyForecast)
+		score float64
+		level string
+		// contributions of past rain, forecast rain, probability and snowmelt
+		contrib [4]float64
+	}{
+		{
+			name:  "dry",
+			level: LevelLow,
+		},
+		{
+			name: "past rain",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset == -10 {
+					h.Precipitation = 50
+				}
+			},
+			score: 0.175, level: LevelLow,
+			contrib: [4]float64{0.175, 0, 0, 0},
+		},
+		{
+			name: "the current hour counts as past",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset == 0 {
+					h.Precipitation = 100
+				}
+			},
+			score: 0.35, level: LevelMedium,
+			contrib: [4]float64{0.35, 0, 0, 0},
+		},
+		{
+			name: "forecast rain and probability",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset == 5 {
+					h.Precipitation, h.PrecipProb = 100, 0.9
+				}
+			},
+			score: 0.435, level: LevelMedium,
+			contrib: [4]float64{0, 0.3, 0.135, 0},
+		},
+		{
+			name: "outside both windows",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset == -73 || offset == 48 {
+					h.Precipitation, h.PrecipProb = 100, 1
+				}
+			},
+			level: LevelLow,
+		},
+		{
+			name: "snowmelt above freezing",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset < -5 {
+					h.SnowDepth = 0.05
+				}
+			},
+			score: 0.075, level: LevelLow,
+			contrib: [4]float64{0, 0, 0, 0.075},
+		},
+		{
+			name: "snow lost below freezing is not melt",
+			set: func(offset int, h *model.HourlyForecast) {
+				if offset < -5 {
+					h.SnowDepth = 0.05
+				}
+				h.Temperature = -2
+			},
+			level: LevelLow,
+		},
+		{
+			name: "every factor saturated",
+			set: func(offset int, h *model.HourlyForecast) {
+				switch {
+				case offset == -1:
+					h.Precipitation = 200
+				case offset == 1:
+					h.Precipitation, h.PrecipProb = 100, 1
+				}
+				if offset < -2 {
+					h.SnowDepth = 0.2
+				}
+			},
+			score: 1, level: LevelHigh,
+			contrib: [4]float64{0.35, 0.3, 0.15, 0.2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := assess(21, 105, series(now, tt.set), now)
+			if a.Score != tt.score || a.Level != tt.level {
+				t.Errorf("score %v (%s), want %v (%s)", a.Score, a.Level, tt.score, tt.level)
+			}
+			if len(a.Factors) != len(tt.contrib) {
+				t.Fatalf("got %d factors, want %d", len(a.Factors), len(tt.contrib))
+			}
+			for i, f := range a.Factors {
+				if f.Contribution != tt.contrib[i] {
+					t.Errorf("%s contributes %v, want %v", f.Name, f.Contribution, tt.contrib[i])
+				}
+			}
+			if a.Method != MethodPrecipitation || !a.AssessedAt.Equal(now) {
+				t.Errorf("method %s at %v, want %s at %v", a.Method, a.AssessedAt, MethodPrecipitation, now)
+			}
+		})
+	}
+}
+
+// failingSource has no precipitation data; it fails with ctx's error once
+// ctx has ended.
+type failingSource struct{}
+
+func (failingSource) Geocode(ctx context.Context, city string) (model.City, error) {
+	return model.City{Name: city}, nil
+}
+
+func (failingSource) Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (model.Forecast, error) {
+	if ctx.Err() != nil {
+		return model.Forecast{}, ctx.Err()
+	}
+	return model.Forecast{}, errors.New("upstream down")
+}
+
+func TestAssessFallbackIsStored(t *testing.T) {
+	repo := store.NewInMemoryRepository()
+	svc := NewService(failingSource{}, repo, nil)
+
+	a, err := svc.Assess(context.Background(), 10, 106, "Can Tho")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.Method != MethodFallback || a.Level != LevelHigh {
+		t.Errorf("got %s/%s, want the regional fallback", a.Method, a.Level)
+	}
+	if got := repo.QueryFloodResults(context.Background(), store.HistoryQuery{}); len(got) != 1 || got[0].City != "Can Tho" {
+		t.Errorf("stored results = %+v, want the fallback", got)
+	}
+}
+
+func TestAssessCancelledIsNotStored(t *testing.T) {
+	repo := store.NewInMemoryRepository()
+	svc := NewService(failingSource{}, repo, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := svc.Assess(ctx, 10, 106, "Can Tho"); !errors.Is(err, context.Canceled) {
+		t.Errorf("Assess = %v, want %v", err, context.Canceled)
+	}
+	if got := repo.QueryFloodResults(context.Background(), store.HistoryQuery{}); len(got) != 0 {
+		t.Errorf("stored results = %+v, want none", got)
+	}
+}
+
+// fakeRiskAPI answers every point with the same level, probability and error.
+type fakeRiskAPI struct {
+	level string
+	prob  float64
+	err   error
+}
+
+func (f fakeRiskAPI) Risk(ctx context.Context, lat, lon float64) (string, float64, error) {
+	return f.level, f.prob, f.err
+}
+
+func TestAssessRiskAPI(t *testing.T) {
+	tests := []struct {
+		name   string
+		api    fakeRiskAPI
+		method string
+		level  string
+		score  float64
+	}{
+		{"answer is used", fakeRiskAPI{level: "Medium", prob: 0.4567}, MethodExternal, LevelMedium, 0.457},
+		{"error falls back", fakeRiskAPI{err: errors.New("risk API down")}, MethodFallback, LevelHigh, 0.85},
+		{"unknown level falls back", fakeRiskAPI{level: "severe", prob: 0.9}, MethodFallback, LevelHigh, 0.85},
+		{"probability out of range falls back", fakeRiskAPI{level: LevelLow, prob: 1.5}, MethodFallback, LevelHigh, 0.85},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := store.NewInMemoryRepository()
+			svc := NewService(failingSource{}, repo, nil)
+			svc.UseRiskAPI(tt.api)
+
+			a, err := svc.Assess(context.Background(), 10, 106, "Can Tho")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Method != tt.method || a.Level != tt.level || a.Score != tt.score {
+				t.Errorf("got %s/%s/%v, want %s/%s/%v", a.Method, a.Level, a.Score, tt.method, tt.level, tt.score)
+			}
+			if got, ok := repo.LatestFloodResult(context.Background(), "Can Tho"); !ok || got.Method != tt.method {
+				t.Errorf("stored result = %+v, want method %s", got, tt.method)
+			}
+		})
+	}
+}
@@ -0,0 +1,331 @@
+package flood
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+const (
+	// zoneCellSize is the edge of a grid cell in degrees.
+	zoneCellSize = 0.5
+	// Zones spanning more cells than this skip the grid and are checked on
+	// every lookup instead.
+	maxZoneCells = 10000
+)
+
+// Zone is one flood zone polygon loaded from GeoJSON.
+type Zone struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	RiskClass string `json:"riskClass"`
+
+	polygons []polygon
+	bbox     bbox
+	feature  json.RawMessage
+}
+
+// polygon is an outer ring followed by any holes, each as [lon, lat] pairs.
+type polygon [][][2]float64
+
+type bbox struct {
+	minLon, minLat, maxLon, maxLat float64
+}
+
+// world bounds every valid position.
+var world = bbox{-180, -90, 180, 90}
+
+// emptyBBox contains nothing; extending it by a box gives that box.
+func emptyBBox() bbox {
+	return bbox{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+}
+
+func (b bbox) union(o bbox) bbox {
+	return bbox{math.Min(b.minLon, o.minLon), math.Min(b.minLat, o.minLat), math.Max(b.maxLon, o.maxLon), math.Max(b.maxLat, o.maxLat)}
+}
+
+// intersection is the overlap of b and o; it is not valid if they do not
+// overlap.
+func (b bbox) intersection(o bbox) bbox {
+	return bbox{math.Max(b.minLon, o.minLon), math.Max(b.minLat, o.minLat), math.Min(b.maxLon, o.maxLon), math.Min(b.maxLat, o.maxLat)}
+}
+
+// valid reports whether b contains any point; NaN edges make it invalid.
+func (b bbox) valid() bool {
+	return b.minLon <= b.maxLon && b.minLat <= b.maxLat
+}
+
+func (b bbox) contains(lon, lat float64) bool {
+	return lon >= b.minLon && lon <= b.maxLon && lat >= b.minLat && lat <= b.maxLat
+}
+
+func (b bbox) intersects(o bbox) bool {
+	return b.minLon <= o.maxLon && o.minLon <= b.maxLon && b.minLat <= o.maxLat && o.minLat <= b.maxLat
+}
+
+// ZoneIndex answers point and bounding-box queries over flood zones using a
+// uniform grid: each cell lists the zones whose bounding box overlaps it.
+// Queries are clamped to bounds, the box around every zone.
+type ZoneIndex struct {
+	zones  []*Zone
+	bounds bbox
+	grid   map[[2]int][]*Zone
+	large  []*Zone
+}
+
+// LoadZones reads a GeoJSON FeatureCollection of Polygon/MultiPolygon
+// features. The risk class is taken from the risk_class, riskClass or risk
+// property.
+func LoadZones(path string) (*ZoneIndex, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read flood zones: %w", err)
+	}
+	var fc struct {
+		Type     string            `json:"type"`
+		Features []json.RawMessage `json:"features"`
+	}
+	if err := json.Unmarshal(raw, &fc); err != nil {
+		return nil, fmt.Errorf("invalid flood zones geojson: %w", err)
+	}
+	if fc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("invalid flood zones geojson: expected FeatureCollection, got %q", fc.Type)
+	}
+	zones := make([]*Zone, 0, len(fc.Features))
+	for i, f := range fc.Features {
+		z, err := parseZone(f, i)
+		if err != nil {
+			return nil, err
+		}
+		zones = append(zones, z)
+	}
+	return NewZoneIndex(zones), nil
+}
+
+func parseZone(raw json.RawMessage, i int) (*Zone, error) {
+	var f struct {
+		ID         interface{}            `json:"id"`
+		Properties map[string]interface{} `json:"properties"`
+		Geometry   struct {
+			Type        string          `json:"type"`
+			Coordinates json.RawMessage `json:"coordinates"`
+		} `json:"geometry"`
+	}
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("flood zone %d: %w", i, err)
+	}
+	z := &Zone{feature: raw}
+	switch f.Geometry.Type {
+	case "Polygon":
+		var p polygon
+		if err := json.Unmarshal(f.Geometry.Coordinates, &p); err != nil {
+			return nil, fmt.Errorf("flood zone %d: %w", i, err)
+		}
+		z.polygons = []polygon{p}
+	case "MultiPolygon":
+		if err := json.Unmarshal(f.Geometry.Coordinates, &z.polygons); err != nil {
+			return nil, fmt.Errorf("flood zone %d: %w", i, err)
+		}
+	default:
+		return nil, fmt.Errorf("flood zone %d: unsupported geometry %q", i, f.Geometry.Type)
+	}
+	if err := validatePolygons(z.polygons); err != nil {
+		return nil, fmt.Errorf("flood zone %d: %w", i, err)
+	}
+
+	z.ID = stringProp(f.Properties, "id")
+	if f.ID != nil {
+		z.ID = fmt.Sprint(f.ID)
+	}
+	if z.ID == "" {
+		z.ID = fmt.Sprintf("zone-%d", i)
+	}
+	z.Name = stringProp(f.Properties, "name")
+	z.RiskClass = strings.ToLower(stringProp(f.Properties, "risk_class", "riskClass", "risk"))
+	z.bbox = polygonsBBox(z.polygons)
+	return z, nil
+}
+
+// validatePolygons checks the shape the lookups rely on: at least one
+// polygon, each with an outer ring, at least four positions in every ring,
+// as GeoJSON requires, and every position a valid longitude and latitude.
+func validatePolygons(polys []polygon) error {
+	if len(polys) == 0 {
+		return fmt.Errorf("empty geometry")
+	}
+	for j, p := range polys {
+		if len(p) == 0 {
+			return fmt.Errorf("polygon %d has no rings", j)
+		}
+		for k, ring := range p {
+			if len(ring) < 4 {
+				return fmt.Errorf("polygon %d ring %d has %d positions, want at least 4", j, k, len(ring))
+			}
+			for _, pt := range ring {
+				if !world.contains(pt[0], pt[1]) {
+					return fmt.Errorf("polygon %d ring %d has position %v outside [-180,180]x[-90,90]", j, k, pt)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func stringProp(props map[string]interface{}, keys ...string) string {
+	for _, k := range keys {
+		if v, ok := props[k]; ok && v != nil {
+			return fmt.Sprint(v)
+		}
+	}
+	return ""
+}
+
+func polygonsBBox(polys []polygon) bbox {
+	b := emptyBBox()
+	for _, p := range polys {
+		for _, pt := range p[0] {
+			b = b.union(bbox{pt[0], pt[1], pt[0], pt[1]})
+		}
+	}
+	return b
+}
+
+// NewZoneIndex builds the grid over zones.
+func NewZoneIndex(zones []*Zone) *ZoneIndex {
+	idx := &ZoneIndex{zones: zones, bounds: emptyBBox(), grid: make(map[[2]int][]*Zone)}
+	for _, z := range zones {
+		idx.bounds = idx.bounds.union(z.bbox)
+		x0, y0, x1, y1, ok := cellRange(z.bbox)
+		if !ok {
+			idx.large = append(idx.large, z)
+			continue
+		}
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				idx.grid[[2]int{x, y}] = append(idx.grid[[2]int{x, y}], z)
+			}
+		}
+	}
+	return idx
+}
+
+// cellRange returns the cells covering b, or false if there are more than
+// maxZoneCells of them. They are counted in float64 so that no box can
+// overflow the count.
+func cellRange(b bbox) (x0, y0, x1, y1 int, ok bool) {
+	fx0, fy0 := math.Floor(b.minLon/zoneCellSize), math.Floor(b.minLat/zoneCellSize)
+	fx1, fy1 := math.Floor(b.maxLon/zoneCellSize), math.Floor(b.maxLat/zoneCellSize)
+	if !((fx1-fx0+1)*(fy1-fy0+1) <= maxZoneCells) {
+		return 0, 0, 0, 0, false
+	}
+	return int(fx0), int(fy0), int(fx1), int(fy1), true
+}
+
+func cellOf(lon, lat float64) (int, int) {
+	return int(math.Floor(lon / zoneCellSize)), int(math.Floor(lat / zoneCellSize))
+}
+
+// Len returns the number of loaded zones.
+func (idx *ZoneIndex) Len() int {
+	return len(idx.zones)
+}
+
+// Containing returns the zones whose polygons contain the point.
+func (idx *ZoneIndex) Containing(lat, lon float64) []*Zone {
+	var out []*Zone
+	if !idx.bounds.contains(lon, lat) {
+		return out
+	}
+	x, y := cellOf(lon, lat)
+	for _, list := range [][]*Zone{idx.grid[[2]int{x, y}], idx.large} {
+		for _, z := range list {
+			if z.bbox.contains(lon, lat) && z.contains(lon, lat) {
+				out = append(out, z)
+			}
+		}
+	}
+	return out
+}
+
+// Within returns the zones whose bounding box intersects the given box,
+// ordered by ID.
+func (idx *ZoneIndex) Within(minLon, minLat, maxLon, maxLat float64) []*Zone {
+	q := bbox{minLon, minLat, maxLon, maxLat}.intersection(idx.bounds)
+	var out []*Zone
+	if !q.valid() {
+		return out
+	}
+	seen := make(map[*Zone]bool)
+	add := func(z *Zone) {
+		if !seen[z] && z.bbox.intersects(q) {
+			seen[z] = true
+			out = append(out, z)
+		}
+	}
+	x0, y0, x1, y1, ok := cellRange(q)
+	if !ok {
+		// Scanning the grid would cost more than checking every zone.
+		for _, z := range idx.zones {
+			add(z)
+		}
+	} else {
+		for x := x0; x <= x1; x++ {
+			for y := y0; y <= y1; y++ {
+				for _, z := range idx.grid[[2]int{x, y}] {
+					add(z)
+				}
+			}
+		}
+		for _, z := range idx.large {
+			add(z)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Feature returns the zone's original GeoJSON feature.
+func (z *Zone) Feature() json.RawMessage {
+	return z.feature
+}
+
+func (z *Zone) ref() model.FloodZone {
+	return model.FloodZone{ID: z.ID, Name: z.Name, RiskClass: z.RiskClass}
+}
+
+func (z *Zone) contains(lon, lat float64) bool {
+	for _, p := range z.polygons {
+		if !ringContains(p[0], lon, lat) {
+			continue
+		}
+		inHole := false
+		for _, hole := range p[1:] {
+			if ringContains(hole, lon, lat) {
+				inHole = true
+				break
+			}
+		}
+		if !inHole {
+			return true
+		}
+	}
+	return false
+}
+
+// ringContains is the even-odd ray casting test.
+func ringContains(ring [][2]float64, lon, lat float64) bool {
+	in := false
+	for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+		xi, yi := ring[i][0], ring[i][1]
+		xj, yj := ring[j][0], ring[j][1]
+		if (yi > lat) != (yj > lat) && lon < (xj-xi)*(lat-yi)/(yj-yi)+xi {
+			in = !in
+		}
+	}
+	return in
+}
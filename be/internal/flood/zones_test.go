@@ -0,0 +1,159 @@
+package flood
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestParseZoneGeometry(t *testing.T) {
+	square := `[[105,21],[106,21],[106,22],[105,22],[105,21]]`
+	tests := []struct {
+		name     string
+		geometry string
+		err      string
+	}{
+		{"polygon", `{"type":"Polygon","coordinates":[` + square + `]}`, ""},
+		{"multipolygon", `{"type":"MultiPolygon","coordinates":[[` + square + `],[` + square + `]]}`, ""},
+		{"no polygons", `{"type":"MultiPolygon","coordinates":[]}`, "empty geometry"},
+		{"polygon without rings", `{"type":"Polygon","coordinates":[]}`, "polygon 0 has no rings"},
+		{"empty polygon in multipolygon", `{"type":"MultiPolygon","coordinates":[[` + square + `],[]]}`, "polygon 1 has no rings"},
+		{"short ring", `{"type":"Polygon","coordinates":[[[105,21],[106,21],[105,21]]]}`, "polygon 0 ring 0 has 3 positions"},
+		{"short hole", `{"type":"Polygon","coordinates":[` + square + `,[]]}`, "polygon 0 ring 1 has 0 positions"},
+		{"unsupported type", `{"type":"Point","coordinates":[105,21]}`, "unsupported geometry"},
+		{"longitude out of range", `{"type":"Polygon","coordinates":[[[105,21],[1e18,21],[106,22],[105,21]]]}`, "outside [-180,180]x[-90,90]"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw := `{"type":"Feature","properties":{"risk_class":"High"},"geometry":` + tt.geometry + `}`
+			z, err := parseZone([]byte(raw), 3)
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("parseZone: %v", err)
+				}
+				if z.ID != "zone-3" || z.RiskClass != "high" || !z.contains(105.5, 21.5) {
+					t.Errorf("zone = %+v, want zone-3 (high) around 105.5,21.5", z)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("parseZone error = %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
+
+// testZone parses a zone with the given id and geometry.
+func testZone(t *testing.T, id, geometry string) *Zone {
+	t.Helper()
+	raw := fmt.Sprintf(`{"type":"Feature","id":%q,"properties":{},"geometry":%s}`, id, geometry)
+	z, err := parseZone([]byte(raw), 0)
+	if err != nil {
+		t.Fatalf("zone %s: %v", id, err)
+	}
+	return z
+}
+
+// square is a closed ring from (lon,lat) to (lon+size,lat+size).
+func square(lon, lat, size float64) string {
+	return fmt.Sprintf(`[[%v,%v],[%v,%v],[%v,%v],[%v,%v],[%v,%v]]`,
+		lon, lat, lon+size, lat, lon+size, lat+size, lon, lat+size, lon, lat)
+}
+
+func testIndex(t *testing.T) *ZoneIndex {
+	t.Helper()
+	return NewZoneIndex([]*Zone{
+		testZone(t, "square", `{"type":"Polygon","coordinates":[`+square(105, 21, 1)+`]}`),
+		testZone(t, "donut", `{"type":"Polygon","coordinates":[`+square(100, 10, 4)+`,`+square(101, 11, 2)+`]}`),
+		testZone(t, "multi", `{"type":"MultiPolygon","coordinates":[[`+square(0, 0, 1)+`],[`+square(10, 10, 1)+`]]}`),
+	})
+}
+
+func zoneIDs(zones []*Zone) string {
+	ids := make([]string, len(zones))
+	for i, z := range zones {
+		ids[i] = z.ID
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestZoneIndexContaining(t *testing.T) {
+	idx := testIndex(t)
+	tests := []struct {
+		name     string
+		lat, lon float64
+		want     string
+	}{
+		{"inside", 21.5, 105.5, "square"},
+		{"outside", 21.5, 107, ""},
+		{"in the ring around a hole", 10.5, 100.5, "donut"},
+		{"in a hole", 12, 102, ""},
+		{"first part of a multipolygon", 0.5, 0.5, "multi"},
+		{"second part of a multipolygon", 10.5, 10.5, "multi"},
+		{"between the parts of a multipolygon", 5, 5, ""},
+		{"outside the index bounds", 80, 170, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := zoneIDs(idx.Containing(tt.lat, tt.lon)); got != tt.want {
+				t.Errorf("Containing(%v, %v) = %q, want %q", tt.lat, tt.lon, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZoneIndexWithin(t *testing.T) {
+	idx := testIndex(t)
+	tests := []struct {
+		name string
+		box  [4]float64
+		want string
+	}{
+		{"around one zone", [4]float64{104, 20, 107, 23}, "square"},
+		{"touching an edge", [4]float64{106, 22, 107, 23}, "square"},
+		{"over a hole", [4]float64{101.5, 11.5, 102.5, 12.5}, "donut"},
+		{"over two zones", [4]float64{-1, -1, 101, 11}, "donut,multi"},
+		{"no zone", [4]float64{50, 50, 60, 60}, ""},
+		{"outside the index bounds", [4]float64{-180, -90, -170, -80}, ""},
+		{"oversized", [4]float64{-1e18, 0, 1e18, 1}, "multi"},
+		{"everything", [4]float64{-180, -90, 180, 90}, "donut,multi,square"},
+		{"NaN", [4]float64{math.NaN(), 0, 180, 90}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := tt.box
+			if got := zoneIDs(idx.Within(b[0], b[1], b[2], b[3])); got != tt.want {
+				t.Errorf("Within(%v) = %q, want %q", b, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewZoneIndexCells(t *testing.T) {
+	wide := testZone(t, "wide", `{"type":"Polygon","coordinates":[`+square(0, 0, 20)+`]}`)
+	huge := testZone(t, "huge", `{"type":"Polygon","coordinates":[`+square(-170, -80, 160)+`]}`)
+	idx := NewZoneIndex([]*Zone{wide, huge})
+
+	// 20° is 40 cells of 0.5° each way, and the far edge starts one more.
+	if n := len(idx.grid); n != 41*41 {
+		t.Errorf("grid has %d cells, want %d", n, 41*41)
+	}
+	for _, cell := range [][2]int{{0, 0}, {20, 20}, {40, 40}} {
+		if got := zoneIDs(idx.grid[cell]); got != "wide" {
+			t.Errorf("cell %v = %q, want wide", cell, got)
+		}
+	}
+	if got := zoneIDs(idx.large); got != "huge" {
+		t.Errorf("large = %q, want huge", got)
+	}
+	if got := zoneIDs(idx.Containing(19.9, 19.9)); got != "wide" {
+		t.Errorf("Containing(19.9, 19.9) = %q, want wide", got)
+	}
+	if got := zoneIDs(idx.Containing(-50, -100)); got != "huge" {
+		t.Errorf("Containing(-50, -100) = %q, want huge", got)
+	}
+	if got := zoneIDs(idx.Within(-100, -60, -99, -59)); got != "huge" {
+		t.Errorf("Within a corner of huge = %q, want huge", got)
+	}
+}
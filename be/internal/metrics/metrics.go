@@ -0,0 +1,204 @@
+// Package metrics defines weatherd's Prometheus instrumentation: HTTP
+// request metrics, cache hit/miss counters, upstream latency and errors,
+// history compaction runs and repository size gauges. Everything is
+// registered on the default registry and served by Handler.
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "weatherd"
+
+// Cache names and lookup results used as label values.
+const (
+	CacheWeather  = "weather"
+	CacheForecast = "forecast"
+
+	ResultHit   = "hit"
+	ResultStale = "stale"
+	ResultMiss  = "miss"
+)
+
+// What history compaction did with an entry, used as label values.
+const (
+	CompactHourly  = "hourly"
+	CompactDaily   = "daily"
+	CompactExpired = "expired"
+	CompactTrimmed = "trimmed"
+)
+
+var (
+	httpRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests served, by method, route and status.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "HTTP request latency, by method, route and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	cacheLookups = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "cache_lookups_total",
+		Help:      "Repository cache lookups, by cache and result (hit, stale, miss).",
+	}, []string{"cache", "result"})
+
+	upstreamDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "upstream_request_duration_seconds",
+		Help:      "Latency of each upstream HTTP attempt, by provider host.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"provider"})
+
+	upstreamErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "upstream_errors_total",
+		Help:      "Failed upstream attempts, by provider host and reason.",
+	}, []string{"provider", "reason"})
+
+	historyCompactions = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "history_compactions_total",
+		Help:      "History compaction runs, by result (ok, error).",
+	}, []string{"result"})
+
+	historyCompactionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "history_compaction_duration_seconds",
+		Help:      "Duration of history compaction runs.",
+		Buckets:   prometheus.DefBuckets,
+	})
+
+	historyCompacted = promauto.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "history_compacted_snapshots_total",
+		Help:      "History entries folded into hourly or daily aggregates, or removed as expired or over the per-city limit.",
+	}, []string{"action"})
+
+	floodResultsTrimmed = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "flood_results_trimmed_total",
+		Help:      "Stored flood assessments deleted by compaction as too old or over the per-city limit.",
+	})
+
+	historyLastCompaction = promauto.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "history_last_compaction_timestamp_seconds",
+		Help:      "Unix time the last history compaction run finished.",
+	})
+)
+
+// Handler serves the default registry in the Prometheus text format.
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
+
+// Middleware records the count and latency of every request. Requests that
+// match no route are reported under route "unmatched" to keep label
+// cardinality bounded.
+func Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		status := strconv.Itoa(c.Writer.Status())
+		httpRequests.WithLabelValues(c.Request.Method, route, status).Inc()
+		httpDuration.WithLabelValues(c.Request.Method, route, status).Observe(time.Since(start).Seconds())
+	}
+}
+
+// CacheLookup counts one cache lookup.
+func CacheLookup(cache, result string) {
+	cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// UpstreamAttempt records the latency of one upstream HTTP attempt.
+func UpstreamAttempt(provider string, d time.Duration) {
+	upstreamDuration.WithLabelValues(provider).Observe(d.Seconds())
+}
+
+// UpstreamError counts a failed upstream attempt, or a call refused by an
+// open circuit breaker.
+func UpstreamError(provider, reason string) {
+	upstreamErrors.WithLabelValues(provider, reason).Inc()
+}
+
+// HistoryCompaction records one history compaction run, failed if any city
+// could not be compacted.
+func HistoryCompaction(d time.Duration, failed bool) {
+	result := "ok"
+	if failed {
+		result = "error"
+	}
+	historyCompactions.WithLabelValues(result).Inc()
+	historyCompactionDuration.Observe(d.Seconds())
+	historyLastCompaction.SetToCurrentTime()
+}
+
+// HistoryCompacted counts n history entries handled by a compaction action.
+func HistoryCompacted(action string, n int) {
+	if n > 0 {
+		historyCompacted.WithLabelValues(action).Add(float64(n))
+	}
+}
+
+// FloodResultsTrimmed counts n flood assessments deleted by compaction.
+func FloodResultsTrimmed(n int) {
+	if n > 0 {
+		floodResultsTrimmed.Add(float64(n))
+	}
+}
+
+// RegisterRepository exports the repository's sizes as gauges, read from
+// repo.Stats on each scrape.
+func RegisterRepository(repo store.WeatherRepository) {
+	prometheus.MustRegister(&repositoryCollector{repo: repo})
+}
+
+var (
+	cachedEntriesDesc = prometheus.NewDesc(namespace+"_cached_entries",
+		"Unexpired weather cache entries (cities and coordinates).", nil, nil)
+	historyCitiesDesc = prometheus.NewDesc(namespace+"_history_cities",
+		"Cities with stored weather history.", nil, nil)
+	historySnapshotsDesc = prometheus.NewDesc(namespace+"_history_snapshots",
+		"Stored weather history snapshots.", nil, nil)
+	floodResultsDesc = prometheus.NewDesc(namespace+"_flood_results",
+		"Stored flood assessments.", nil, nil)
+)
+
+// repositoryCollector reads all size gauges from a single Stats call.
+type repositoryCollector struct {
+	repo store.WeatherRepository
+}
+
+func (c *repositoryCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- cachedEntriesDesc
+	ch <- historyCitiesDesc
+	ch <- historySnapshotsDesc
+	ch <- floodResultsDesc
+}
+
+func (c *repositoryCollector) Collect(ch chan<- prometheus.Metric) {
+	st := c.repo.Stats(context.Background())
+	ch <- prometheus.MustNewConstMetric(cachedEntriesDesc, prometheus.GaugeValue, float64(st.CachedEntries))
+	ch <- prometheus.MustNewConstMetric(historyCitiesDesc, prometheus.GaugeValue, float64(st.HistoryCities))
+	ch <- prometheus.MustNewConstMetric(historySnapshotsDesc, prometheus.GaugeValue, float64(st.HistorySnapshots))
+	ch <- prometheus.MustNewConstMetric(floodResultsDesc, prometheus.GaugeValue, float64(st.FloodResults))
+}
@@ -0,0 +1,52 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+)
+
+// instrumentedRepository counts cache hits and misses on the wrapped
+// repository; every other call passes straight through.
+type instrumentedRepository struct {
+	store.WeatherRepository
+}
+
+// InstrumentRepository wraps repo so its cache lookups are counted.
+func InstrumentRepository(repo store.WeatherRepository) store.WeatherRepository {
+	return instrumentedRepository{repo}
+}
+
+func (r instrumentedRepository) Get(ctx context.Context, city string) (model.WeatherDetails, bool) {
+	data, ok := r.WeatherRepository.Get(ctx, city)
+	CacheLookup(CacheWeather, hitOrMiss(ok))
+	return data, ok
+}
+
+func (r instrumentedRepository) GetRecord(ctx context.Context, city string) (store.CacheRecord, bool) {
+	rec, ok := r.WeatherRepository.GetRecord(ctx, city)
+	switch {
+	case !ok:
+		CacheLookup(CacheWeather, ResultMiss)
+	case rec.Stale(time.Now()):
+		CacheLookup(CacheWeather, ResultStale)
+	default:
+		CacheLookup(CacheWeather, ResultHit)
+	}
+	return rec, ok
+}
+
+func (r instrumentedRepository) GetForecast(ctx context.Context, key string) (model.Forecast, bool) {
+	data, ok := r.WeatherRepository.GetForecast(ctx, key)
+	CacheLookup(CacheForecast, hitOrMiss(ok))
+	return data, ok
+}
+
+func hitOrMiss(ok bool) string {
+	if ok {
+		return ResultHit
+	}
+	return ResultMiss
+}
@@ -0,0 +1,69 @@
+package metrics
+
+import (
+	"context"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	weatherLabels = []string{"city"}
+
+	temperatureDesc = prometheus.NewDesc(namespace+"_weather_temperature_celsius",
+		"Latest cached temperature.", weatherLabels, nil)
+	humidityDesc = prometheus.NewDesc(namespace+"_weather_humidity_percent",
+		"Latest cached relative humidity.", weatherLabels, nil)
+	windSpeedDesc = prometheus.NewDesc(namespace+"_weather_wind_speed_kmh",
+		"Latest cached wind speed.", weatherLabels, nil)
+	pressureDesc = prometheus.NewDesc(namespace+"_weather_pressure_hpa",
+		"Latest cached surface pressure.", weatherLabels, nil)
+	uvIndexDesc = prometheus.NewDesc(namespace+"_weather_uv_index",
+		"Latest cached UV index.", weatherLabels, nil)
+	precipProbDesc = prometheus.NewDesc(namespace+"_weather_precipitation_probability_ratio",
+		"Latest cached precipitation probability (0-1).", weatherLabels, nil)
+	weatherUpdatedDesc = prometheus.NewDesc(namespace+"_weather_updated_timestamp_seconds",
+		"When the cached weather was fetched, as a Unix timestamp.", weatherLabels, nil)
+)
+
+// RegisterWeather exports the latest cached weather of each allowed city as
+// gauges labelled by city. Only cities in the allow-list are exported, to
+// bound cardinality; a city without a cache entry has no series until it is
+// next requested.
+func RegisterWeather(repo store.WeatherRepository, cities []string) {
+	if len(cities) == 0 {
+		return
+	}
+	prometheus.MustRegister(&weatherCollector{repo: repo, cities: cities})
+}
+
+type weatherCollector struct {
+	repo   store.WeatherRepository
+	cities []string
+}
+
+func (c *weatherCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, d := range []*prometheus.Desc{temperatureDesc, humidityDesc, windSpeedDesc, pressureDesc, uvIndexDesc, precipProbDesc, weatherUpdatedDesc} {
+		ch <- d
+	}
+}
+
+func (c *weatherCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, city := range c.cities {
+		rec, ok := c.repo.GetRecord(context.Background(), store.CacheKey(city))
+		if !ok {
+			continue
+		}
+		w := rec.Weather
+		gauge := func(d *prometheus.Desc, v float64) {
+			ch <- prometheus.MustNewConstMetric(d, prometheus.GaugeValue, v, city)
+		}
+		gauge(temperatureDesc, w.Temperature)
+		gauge(humidityDesc, float64(w.Humidity))
+		gauge(windSpeedDesc, w.WindSpeed)
+		gauge(pressureDesc, float64(w.Pressure))
+		gauge(uvIndexDesc, float64(w.UVIndex))
+		gauge(precipProbDesc, w.PrecipProb)
+		gauge(weatherUpdatedDesc, float64(w.UpdatedAt.Unix()))
+	}
+}
@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// FloodAssessment is the flood risk computed for a location
+// swagger:model
+type FloodAssessment struct {
+	City       string        `json:"city,omitempty"`
+	Lat        float64       `json:"lat"`
+	Lon        float64       `json:"lon"`
+	Score      float64       `json:"score"`
+	Level      string        `json:"level"`
+	Factors    []FloodFactor `json:"factors"`
+	Method     string        `json:"method"`
+	Zones      []FloodZone   `json:"zones,omitempty"`
+	AssessedAt time.Time     `json:"assessedAt"`
+}
+
+// FloodZone identifies a mapped flood zone containing an assessed point
+type FloodZone struct {
+	ID        string `json:"id"`
+	Name      string `json:"name,omitempty"`
+	RiskClass string `json:"riskClass"`
+}
+
+// FloodFactor is one input to a flood assessment and how much it added to
+// the score
+type FloodFactor struct {
+	Name         string  `json:"name"`
+	Value        float64 `json:"value"`
+	Unit         string  `json:"unit"`
+	Contribution float64 `json:"contribution"`
+}
@@ -0,0 +1,45 @@
+package model
+
+import "time"
+
+// Forecast is the normalized multi-day forecast for a location
+// swagger:model
+type Forecast struct {
+	City      string           `json:"city"`
+	Lat       float64          `json:"lat"`
+	Lon       float64          `json:"lon"`
+	Timezone  string           `json:"timezone"`
+	Hourly    []HourlyForecast `json:"hourly,omitempty"`
+	Daily     []DailyForecast  `json:"daily,omitempty"`
+	UpdatedAt time.Time        `json:"updatedAt"`
+}
+
+// HourlyForecast holds the forecast values for a single hour
+type HourlyForecast struct {
+	Time          time.Time `json:"time"`
+	Temperature   float64   `json:"temperature"`
+	FeelsLike     float64   `json:"feelsLike"`
+	Humidity      int       `json:"humidity"`
+	WindSpeed     float64   `json:"windSpeed"`
+	WindDir       string    `json:"windDir"`
+	Visibility    float64   `json:"visibility"`
+	Pressure      int       `json:"pressure"`
+	UVIndex       float64   `json:"uvIndex"`
+	CloudCover    int       `json:"cloudCover"`
+	PrecipProb    float64   `json:"precipProb"`
+	Precipitation float64   `json:"precipitation"`
+	Rain          float64   `json:"rain"`
+	Snow          float64   `json:"snow"`
+	SnowDepth     float64   `json:"snowDepth"`
+}
+
+// DailyForecast holds the aggregated forecast values for a single day
+type DailyForecast struct {
+	Date       time.Time `json:"date"`
+	TempMin    float64   `json:"tempMin"`
+	TempMax    float64   `json:"tempMax"`
+	PrecipSum  float64   `json:"precipSum"`
+	UVIndexMax float64   `json:"uvIndexMax"`
+	Sunrise    time.Time `json:"sunrise"`
+	Sunset     time.Time `json:"sunset"`
+}
@@ -21,4 +21,11 @@ type WeatherDetails struct {
 	Rain        float64   `json:"rain"`
 	Snow        float64   `json:"snow"`
 	UpdatedAt   time.Time `json:"updatedAt"`
+	// Resolution is "hour" or "day" for history entries that average the
+	// Samples snapshots taken in that period; it is empty for raw snapshots.
+	Resolution string `json:"resolution,omitempty"`
+	Samples    int    `json:"samples,omitempty"`
+	// Stale is set on responses served past the soft cache TTL while a
+	// background refresh is in progress.
+	Stale bool `json:"stale,omitempty"`
 }
@@ -0,0 +1,124 @@
+package retention
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/metrics"
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Compactor applies a Policy to every city's history and flood results in
+// the repository.
+type Compactor struct {
+	repo   store.WeatherRepository
+	policy Policy
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewCompactor builds a compactor for repo. Call Start to run it in the
+// background, or Run for a single pass.
+func NewCompactor(repo store.WeatherRepository, policy Policy) *Compactor {
+	return &Compactor{repo: repo, policy: policy}
+}
+
+// Run compacts every city once and then trims the flood results. A city
+// that cannot be rewritten is logged and skipped; its error is included in
+// the returned one.
+func (c *Compactor) Run(ctx context.Context) (Result, error) {
+	ctx, span := tracing.Start(ctx, "history.compact")
+	start := time.Now()
+	var total Result
+	var errs []error
+	cities := c.repo.HistoryCities(ctx)
+	for _, city := range cities {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		var res Result
+		err := c.repo.CompactHistory(ctx, city, func(list []model.WeatherDetails) ([]model.WeatherDetails, bool) {
+			var out []model.WeatherDetails
+			out, res = c.policy.Apply(list, time.Now().UTC())
+			return out, res.Changed()
+		})
+		if err != nil {
+			util.Log(ctx).Warn("history compaction failed", "city", city, "err", err)
+			errs = append(errs, err)
+			continue
+		}
+		total.add(res)
+	}
+	if ctx.Err() == nil && (c.policy.FloodMaxAge > 0 || c.policy.FloodMaxCount > 0) {
+		var cutoff time.Time
+		if c.policy.FloodMaxAge > 0 {
+			cutoff = time.Now().Add(-c.policy.FloodMaxAge)
+		}
+		n, err := c.repo.TrimFloodResults(ctx, cutoff, c.policy.FloodMaxCount)
+		if err != nil {
+			util.Log(ctx).Warn("flood result trim failed", "err", err)
+			errs = append(errs, err)
+		}
+		total.Flood += n
+	}
+	err := errors.Join(errs...)
+	span.SetAttributes(
+		attribute.Int("cities", len(cities)),
+		attribute.Int("hourly", total.Hourly),
+		attribute.Int("daily", total.Daily),
+		attribute.Int("expired", total.Expired),
+		attribute.Int("trimmed", total.Trimmed),
+		attribute.Int("flood", total.Flood),
+	)
+	tracing.End(span, err)
+
+	metrics.HistoryCompacted(metrics.CompactHourly, total.Hourly)
+	metrics.HistoryCompacted(metrics.CompactDaily, total.Daily)
+	metrics.HistoryCompacted(metrics.CompactExpired, total.Expired)
+	metrics.HistoryCompacted(metrics.CompactTrimmed, total.Trimmed)
+	metrics.FloodResultsTrimmed(total.Flood)
+	metrics.HistoryCompaction(time.Since(start), err != nil)
+	log := util.Log(ctx).Debug
+	if total.Changed() {
+		log = util.Log(ctx).Info
+	}
+	log("history compacted", "cities", len(cities),
+		"hourly", total.Hourly, "daily", total.Daily, "expired", total.Expired, "trimmed", total.Trimmed, "flood", total.Flood,
+		"duration_ms", time.Since(start).Milliseconds())
+	return total, err
+}
+
+// Start runs a compaction now and then every interval until Stop.
+func (c *Compactor) Start(interval time.Duration) {
+	ctx, cancel := context.WithCancel(util.WithLogAttrs(context.Background(), "job", "history-compaction"))
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go func() {
+		defer close(c.done)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			_, _ = c.Run(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Stop cancels a compaction in progress and waits for the job to exit.
+func (c *Compactor) Stop() {
+	if c.cancel == nil {
+		return
+	}
+	c.cancel()
+	<-c.done
+}
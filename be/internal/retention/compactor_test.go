@@ -0,0 +1,96 @@
+package retention
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestCompactorRun(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp, err := tracing.Install(context.Background(), exp, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prev)
+	})
+
+	ctx := context.Background()
+	repo := store.NewInMemoryRepository()
+	old := time.Now().UTC().Add(-48 * time.Hour).Truncate(time.Hour)
+	for i := 0; i < 3; i++ {
+		repo.AppendHistory(ctx, "Hanoi", model.WeatherDetails{City: "Hanoi", Temperature: 20, UpdatedAt: old.Add(time.Duration(i) * time.Minute)})
+	}
+	repo.AppendHistory(ctx, "Oslo", model.WeatherDetails{City: "Oslo", UpdatedAt: time.Now()})
+
+	c := NewCompactor(repo, Policy{RawAge: 24 * time.Hour})
+	res, err := c.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Hourly != 3 {
+		t.Errorf("Hourly = %d, want 3", res.Hourly)
+	}
+	if got := repo.ListHistory(ctx, "Hanoi"); len(got) != 1 || got[0].Resolution != ResolutionHour || got[0].Samples != 3 {
+		t.Errorf("Hanoi history = %+v, want one hourly entry of 3 samples", got)
+	}
+	if got := repo.ListHistory(ctx, "Oslo"); len(got) != 1 || got[0].Resolution != "" {
+		t.Errorf("Oslo history = %+v, want its recent snapshot untouched", got)
+	}
+
+	if err := tp.ForceFlush(ctx); err != nil {
+		t.Fatal(err)
+	}
+	var found bool
+	for _, s := range exp.GetSpans() {
+		if s.Name != "history.compact" {
+			continue
+		}
+		found = true
+		attrs := make(map[string]int64)
+		for _, kv := range s.Attributes {
+			attrs[string(kv.Key)] = kv.Value.AsInt64()
+		}
+		if attrs["cities"] != 2 || attrs["hourly"] != 3 {
+			t.Errorf("span attributes = %v, want 2 cities and 3 hourly", attrs)
+		}
+	}
+	if !found {
+		t.Error("no history.compact span")
+	}
+}
+
+func TestCompactorTrimsFloodResults(t *testing.T) {
+	ctx := context.Background()
+	repo := store.NewInMemoryRepository()
+	now := time.Now()
+	for i := 0; i < 4; i++ {
+		repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: float64(i), AssessedAt: now.Add(-time.Duration(i) * time.Hour)})
+	}
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Oslo", AssessedAt: now.Add(-48 * time.Hour)})
+
+	c := NewCompactor(repo, Policy{FloodMaxAge: 24 * time.Hour, FloodMaxCount: 2})
+	res, err := c.Run(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Flood != 3 {
+		t.Errorf("Flood = %d, want 3", res.Flood)
+	}
+	got := repo.QueryFloodResults(ctx, store.HistoryQuery{})
+	if len(got) != 2 || got[0].Score != 1 || got[1].Score != 0 {
+		t.Errorf("flood results = %+v, want the two newest of Hanoi", got)
+	}
+	if latest, ok := repo.LatestFloodResult(ctx, "hanoi"); !ok || latest.Score != 0 {
+		t.Errorf("LatestFloodResult = %+v, %v; want the newest", latest, ok)
+	}
+}
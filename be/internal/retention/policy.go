@@ -0,0 +1,255 @@
+// Package retention keeps weather history bounded. Recent snapshots are kept
+// as recorded, older ones are averaged into hourly and then daily entries,
+// and the oldest are dropped, by a background job that rewrites each city's
+// history in the repository.
+package retention
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+// Resolutions of aggregated history entries.
+const (
+	ResolutionHour = "hour"
+	ResolutionDay  = "day"
+)
+
+// Policy bounds one city's history. Raw snapshots from hours that ended
+// more than RawAge ago are averaged into one entry per hour, entries from
+// days (UTC) that ended more than HourlyAge ago into one per day, and
+// entries older than MaxAge are dropped. If more than MaxCount entries
+// remain, raw snapshots are folded into hours ahead of time, oldest first,
+// then hours into days, and only then are the oldest entries dropped.
+// Stored flood assessments older than FloodMaxAge, or beyond the newest
+// FloodMaxCount of a city, are dropped too. A zero field skips that step.
+type Policy struct {
+	RawAge    time.Duration
+	HourlyAge time.Duration
+	MaxAge    time.Duration
+	MaxCount  int
+
+	FloodMaxAge   time.Duration
+	FloodMaxCount int
+}
+
+// Result counts the history entries each step of a compaction took out:
+// folded into an hourly or daily aggregate, expired, or trimmed to the
+// per-city limit. Flood counts the flood assessments deleted.
+type Result struct {
+	Hourly  int `json:"hourly"`
+	Daily   int `json:"daily"`
+	Expired int `json:"expired"`
+	Trimmed int `json:"trimmed"`
+	Flood   int `json:"flood"`
+
+	merged bool
+}
+
+// Changed reports whether the compaction changed anything.
+func (r Result) Changed() bool {
+	return r.merged || r.Hourly+r.Daily+r.Expired+r.Trimmed+r.Flood > 0
+}
+
+func (r *Result) add(o Result) {
+	r.Hourly += o.Hourly
+	r.Daily += o.Daily
+	r.Expired += o.Expired
+	r.Trimmed += o.Trimmed
+	r.Flood += o.Flood
+	r.merged = r.merged || o.merged
+}
+
+type bucketKey struct {
+	resolution string
+	start      time.Time
+}
+
+// Apply compacts one city's history as of now and returns it oldest first.
+// Applying it again to its own output changes nothing until time moves
+// entries across a cut-off.
+func (p Policy) Apply(list []model.WeatherDetails, now time.Time) ([]model.WeatherDetails, Result) {
+	var res Result
+	out := make([]model.WeatherDetails, 0, len(list))
+	buckets := make(map[bucketKey][]model.WeatherDetails)
+	var order []bucketKey
+	add := func(k bucketKey, e model.WeatherDetails) {
+		if _, ok := buckets[k]; !ok {
+			order = append(order, k)
+		}
+		buckets[k] = append(buckets[k], e)
+	}
+	rawCut, hourlyCut, maxCut := now.Add(-p.RawAge), now.Add(-p.HourlyAge), now.Add(-p.MaxAge)
+	for _, e := range list {
+		hour, day := periodStart(e.UpdatedAt, ResolutionHour), periodStart(e.UpdatedAt, ResolutionDay)
+		switch {
+		case p.MaxAge > 0 && periodEnd(e).Before(maxCut):
+			res.Expired++
+		// Only whole hours and days are folded, and existing aggregates go
+		// back into their bucket, so late snapshots merge into them rather
+		// than beside them.
+		case e.Resolution == ResolutionDay || p.HourlyAge > 0 && !day.AddDate(0, 0, 1).After(hourlyCut):
+			add(bucketKey{ResolutionDay, day}, e)
+		case e.Resolution == ResolutionHour || p.RawAge > 0 && !hour.Add(time.Hour).After(rawCut):
+			add(bucketKey{ResolutionHour, hour}, e)
+		default:
+			out = append(out, e)
+		}
+	}
+	for _, k := range order {
+		out = append(out, res.fold(k, buckets[k]))
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].UpdatedAt.Before(out[j].UpdatedAt) })
+	if p.MaxCount > 0 {
+		// Over the limit, coarsen the oldest entries before dropping any,
+		// so the long-term aggregates are the last thing to go.
+		out = foldEarly(out, ResolutionHour, p.MaxCount, &res)
+		out = foldEarly(out, ResolutionDay, p.MaxCount, &res)
+		if len(out) > p.MaxCount {
+			res.Trimmed = len(out) - p.MaxCount
+			out = out[res.Trimmed:]
+		}
+	}
+	return out, res
+}
+
+// fold aggregates the entries of bucket k, counting those it folds in.
+func (r *Result) fold(k bucketKey, entries []model.WeatherDetails) model.WeatherDetails {
+	for _, e := range entries {
+		if e.Resolution == k.resolution {
+			continue
+		}
+		if k.resolution == ResolutionDay {
+			r.Daily++
+		} else {
+			r.Hourly++
+		}
+	}
+	if len(entries) > 1 {
+		r.merged = true
+	}
+	return aggregate(k, entries)
+}
+
+// foldEarly folds runs of entries finer than resolution into one entry per
+// period, oldest first, until list has at most max entries. list must be
+// sorted by time.
+func foldEarly(list []model.WeatherDetails, resolution string, max int, res *Result) []model.WeatherDetails {
+	excess := len(list) - max
+	if excess <= 0 {
+		return list
+	}
+	out := make([]model.WeatherDetails, 0, len(list))
+	for i := 0; i < len(list); {
+		e := list[i]
+		if excess <= 0 || rank(e.Resolution) > rank(resolution) {
+			out = append(out, e)
+			i++
+			continue
+		}
+		k := bucketKey{resolution, periodStart(e.UpdatedAt, resolution)}
+		j := i + 1
+		for j < len(list) && rank(list[j].Resolution) <= rank(resolution) && periodStart(list[j].UpdatedAt, resolution).Equal(k.start) {
+			j++
+		}
+		if j-i > 1 {
+			out = append(out, res.fold(k, list[i:j]))
+			excess -= j - i - 1
+		} else {
+			out = append(out, e)
+		}
+		i = j
+	}
+	return out
+}
+
+// rank orders resolutions from raw snapshots to days.
+func rank(resolution string) int {
+	switch resolution {
+	case ResolutionHour:
+		return 1
+	case ResolutionDay:
+		return 2
+	}
+	return 0
+}
+
+// periodStart is the start of the UTC hour or day containing t.
+func periodStart(t time.Time, resolution string) time.Time {
+	t = t.UTC()
+	if resolution == ResolutionDay {
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	}
+	return t.Truncate(time.Hour)
+}
+
+// periodEnd is when the period an entry covers ends: its own time for a raw
+// snapshot, the end of the hour or day for an aggregate.
+func periodEnd(e model.WeatherDetails) time.Time {
+	switch e.Resolution {
+	case ResolutionHour:
+		return e.UpdatedAt.Add(time.Hour)
+	case ResolutionDay:
+		return e.UpdatedAt.AddDate(0, 0, 1)
+	}
+	return e.UpdatedAt
+}
+
+// aggregate averages entries, weighting earlier aggregates by their sample
+// count. Fields that cannot be averaged come from the latest entry.
+func aggregate(k bucketKey, entries []model.WeatherDetails) model.WeatherDetails {
+	if len(entries) == 1 && entries[0].Resolution == k.resolution {
+		return entries[0]
+	}
+	var sum struct {
+		temp, feels, humidity, wind, visibility, pressure, uv, cloud, precip, rain, snow float64
+	}
+	samples := 0
+	latest := entries[0]
+	for _, e := range entries {
+		n := e.Samples
+		if n < 1 {
+			n = 1
+		}
+		w := float64(n)
+		samples += n
+		sum.temp += w * e.Temperature
+		sum.feels += w * e.FeelsLike
+		sum.humidity += w * float64(e.Humidity)
+		sum.wind += w * e.WindSpeed
+		sum.visibility += w * e.Visibility
+		sum.pressure += w * float64(e.Pressure)
+		sum.uv += w * float64(e.UVIndex)
+		sum.cloud += w * float64(e.CloudCover)
+		sum.precip += w * e.PrecipProb
+		sum.rain += w * e.Rain
+		sum.snow += w * e.Snow
+		if !e.UpdatedAt.Before(latest.UpdatedAt) {
+			latest = e
+		}
+	}
+	n := float64(samples)
+	return model.WeatherDetails{
+		City:        latest.City,
+		Temperature: sum.temp / n,
+		FeelsLike:   sum.feels / n,
+		Humidity:    int(math.Round(sum.humidity / n)),
+		WindSpeed:   sum.wind / n,
+		WindDir:     latest.WindDir,
+		Visibility:  sum.visibility / n,
+		Pressure:    int(math.Round(sum.pressure / n)),
+		UVIndex:     int(math.Round(sum.uv / n)),
+		Sunrise:     latest.Sunrise,
+		Sunset:      latest.Sunset,
+		CloudCover:  int(math.Round(sum.cloud / n)),
+		PrecipProb:  sum.precip / n,
+		Rain:        sum.rain / n,
+		Snow:        sum.snow / n,
+		UpdatedAt:   k.start,
+		Resolution:  k.resolution,
+		Samples:     samples,
+	}
+}
@@ -0,0 +1,177 @@
+package retention
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+var now = time.Date(2026, 3, 10, 12, 30, 0, 0, time.UTC)
+
+func at(s string) time.Time {
+	t, err := time.Parse("01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return time.Date(2026, t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, time.UTC)
+}
+
+func raw(s string, temp float64) model.WeatherDetails {
+	return model.WeatherDetails{City: "Hanoi", Temperature: temp, UpdatedAt: at(s)}
+}
+
+func agg(resolution, s string, temp float64, samples int) model.WeatherDetails {
+	e := raw(s, temp)
+	e.Resolution, e.Samples = resolution, samples
+	return e
+}
+
+// describe summarizes entries as "<resolution> <time> x<samples> <temp>".
+func describe(list []model.WeatherDetails) []string {
+	out := make([]string, 0, len(list))
+	for _, e := range list {
+		res := e.Resolution
+		if res == "" {
+			res = "raw"
+		}
+		out = append(out, fmt.Sprintf("%s %s x%d %g", res, e.UpdatedAt.Format("01-02 15:04"), e.Samples, e.Temperature))
+	}
+	return out
+}
+
+func TestPolicyApply(t *testing.T) {
+	base := Policy{RawAge: 24 * time.Hour, HourlyAge: 7 * 24 * time.Hour, MaxAge: 30 * 24 * time.Hour}
+	withMax := func(n int) Policy {
+		p := base
+		p.MaxCount = n
+		return p
+	}
+	tests := []struct {
+		name   string
+		policy Policy
+		in     []model.WeatherDetails
+		want   []string
+		res    Result
+	}{
+		{
+			name:   "recent snapshots are kept",
+			policy: base,
+			in:     []model.WeatherDetails{raw("03-10 10:00", 20), raw("03-10 11:00", 21)},
+			want:   []string{"raw 03-10 10:00 x0 20", "raw 03-10 11:00 x0 21"},
+		},
+		{
+			name:   "old snapshots are averaged per hour",
+			policy: base,
+			in:     []model.WeatherDetails{raw("03-09 06:10", 10), raw("03-09 06:20", 20), raw("03-09 06:40", 30), raw("03-09 07:05", 40)},
+			want:   []string{"hour 03-09 06:00 x3 20", "hour 03-09 07:00 x1 40"},
+			res:    Result{Hourly: 4, merged: true},
+		},
+		{
+			name:   "an hour still open at the cut-off stays raw",
+			policy: base,
+			in:     []model.WeatherDetails{raw("03-09 11:50", 10), raw("03-09 12:10", 20)},
+			want:   []string{"hour 03-09 11:00 x1 10", "raw 03-09 12:10 x0 20"},
+			res:    Result{Hourly: 1},
+		},
+		{
+			name:   "a late snapshot merges into its hour",
+			policy: base,
+			in:     []model.WeatherDetails{agg(ResolutionHour, "03-09 06:00", 10, 2), raw("03-09 06:50", 40)},
+			want:   []string{"hour 03-09 06:00 x3 20"},
+			res:    Result{Hourly: 1, merged: true},
+		},
+		{
+			name:   "old hours are averaged per day by sample count",
+			policy: base,
+			in:     []model.WeatherDetails{agg(ResolutionHour, "03-01 06:00", 10, 3), agg(ResolutionHour, "03-01 07:00", 30, 1), raw("03-01 23:59", 20)},
+			want:   []string{"day 03-01 00:00 x5 16"},
+			res:    Result{Daily: 3, merged: true},
+		},
+		{
+			name:   "entries expire once their whole period is past MaxAge",
+			policy: base,
+			in:     []model.WeatherDetails{agg(ResolutionDay, "02-07 00:00", 5, 24), agg(ResolutionDay, "02-08 00:00", 6, 24)},
+			want:   []string{"day 02-08 00:00 x24 6"},
+			res:    Result{Expired: 1},
+		},
+		{
+			name:   "over the limit, recent snapshots are folded before aggregates are touched",
+			policy: withMax(4),
+			in: []model.WeatherDetails{
+				agg(ResolutionDay, "03-01 00:00", 15, 24), agg(ResolutionHour, "03-09 06:00", 20, 6),
+				raw("03-10 11:05", 25), raw("03-10 11:35", 27), raw("03-10 12:05", 28), raw("03-10 12:10", 30),
+			},
+			want: []string{"day 03-01 00:00 x24 15", "hour 03-09 06:00 x6 20", "hour 03-10 11:00 x2 26", "hour 03-10 12:00 x2 29"},
+			res:  Result{Hourly: 4, merged: true},
+		},
+		{
+			name:   "folding stops once under the limit",
+			policy: withMax(4),
+			in:     []model.WeatherDetails{raw("03-10 10:05", 20), raw("03-10 10:35", 22), raw("03-10 11:05", 24), raw("03-10 11:35", 26), raw("03-10 12:05", 28)},
+			want:   []string{"hour 03-10 10:00 x2 21", "raw 03-10 11:05 x0 24", "raw 03-10 11:35 x0 26", "raw 03-10 12:05 x0 28"},
+			res:    Result{Hourly: 2, merged: true},
+		},
+		{
+			name:   "then hours are folded into days",
+			policy: withMax(2),
+			in:     []model.WeatherDetails{agg(ResolutionHour, "03-09 06:00", 10, 1), agg(ResolutionHour, "03-09 07:00", 20, 1), raw("03-10 12:20", 30)},
+			want:   []string{"day 03-09 00:00 x2 15", "raw 03-10 12:20 x0 30"},
+			res:    Result{Daily: 2, merged: true},
+		},
+		{
+			name:   "the oldest entries are dropped as a last resort",
+			policy: withMax(1),
+			in:     []model.WeatherDetails{agg(ResolutionDay, "03-01 00:00", 15, 24), agg(ResolutionDay, "03-02 00:00", 16, 24)},
+			want:   []string{"day 03-02 00:00 x24 16"},
+			res:    Result{Trimmed: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, res := tt.policy.Apply(tt.in, now)
+			if got := describe(out); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Apply =\n%q\nwant\n%q", got, tt.want)
+			}
+			if res != tt.res {
+				t.Errorf("result %+v, want %+v", res, tt.res)
+			}
+			if res.Changed() != (tt.res != Result{}) {
+				t.Errorf("Changed() = %v", res.Changed())
+			}
+			again, res := tt.policy.Apply(out, now)
+			if res.Changed() || !reflect.DeepEqual(again, out) {
+				t.Errorf("second Apply changed the history: %q (%+v)", describe(again), res)
+			}
+		})
+	}
+}
+
+// A long, dense history settles under the limit and stays there as time
+// moves on, with the aggregates outliving the recent snapshots.
+func TestPolicyApplyIsIdempotentOverTime(t *testing.T) {
+	p := Policy{RawAge: 24 * time.Hour, HourlyAge: 7 * 24 * time.Hour, MaxAge: 30 * 24 * time.Hour, MaxCount: 300}
+	start := now.Add(-40 * 24 * time.Hour)
+	var list []model.WeatherDetails
+	for ts := start; ts.Before(now); ts = ts.Add(10 * time.Minute) {
+		list = append(list, model.WeatherDetails{City: "Hanoi", Temperature: 20, UpdatedAt: ts})
+	}
+	for step := 0; step < 48; step++ {
+		t0 := now.Add(time.Duration(step) * time.Hour)
+		list = append(list, model.WeatherDetails{City: "Hanoi", Temperature: 20, UpdatedAt: t0.Add(-time.Minute)})
+		out, _ := p.Apply(list, t0)
+		if len(out) > p.MaxCount {
+			t.Fatalf("step %d: %d entries, want at most %d", step, len(out), p.MaxCount)
+		}
+		if out[0].Resolution != ResolutionDay {
+			t.Fatalf("step %d: oldest entry is %q, want a day aggregate", step, out[0].Resolution)
+		}
+		again, res := p.Apply(out, t0)
+		if res.Changed() || !reflect.DeepEqual(again, out) {
+			t.Fatalf("step %d: second Apply changed the history (%+v)", step, res)
+		}
+		list = out
+	}
+}
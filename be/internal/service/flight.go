@@ -0,0 +1,83 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"golang.org/x/sync/singleflight"
+)
+
+// flightGroup merges concurrent fetches for the same key into one call, like
+// singleflight, but lets each caller give up on its own context. The shared
+// call runs under a context that is cancelled only once every caller waiting
+// on it has gone, so a disconnecting client stops the upstream request
+// unless someone else still wants the result.
+type flightGroup struct {
+	group singleflight.Group
+
+	mu    sync.Mutex
+	calls map[string]*flightCall
+}
+
+type flightCall struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	waiters int
+}
+
+// Do runs fn for key, or joins the call already in flight, and returns its
+// result, or an error if ctx ends first: UpstreamUnavailable when its
+// deadline passed, since the upstream was too slow for this caller, and
+// context.Canceled as is. fn receives a context carrying the values
+// (logger, request ID) of the caller that started it.
+func (g *flightGroup) Do(ctx context.Context, key string, fn func(ctx context.Context) (interface{}, error)) (interface{}, error) {
+	call := g.join(ctx, key)
+	defer g.leave(key, call)
+	ch := g.group.DoChan(key, func() (interface{}, error) {
+		return fn(call.ctx)
+	})
+	select {
+	case res := <-ch:
+		return res.Val, res.Err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, util.UpstreamUnavailable("upstream did not answer in time", ctx.Err())
+		}
+		return nil, ctx.Err()
+	}
+}
+
+func (g *flightGroup) join(ctx context.Context, key string) *flightCall {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.calls == nil {
+		g.calls = make(map[string]*flightCall)
+	}
+	call, ok := g.calls[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &flightCall{ctx: fctx, cancel: cancel}
+		g.calls[key] = call
+	}
+	call.waiters++
+	return call
+}
+
+// leave drops a waiter. The last one out cancels the shared call and makes
+// singleflight forget it, so a later caller starts afresh instead of
+// joining a call that is being torn down.
+func (g *flightGroup) leave(key string, call *flightCall) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	call.waiters--
+	if call.waiters > 0 {
+		return
+	}
+	call.cancel()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+		g.group.Forget(key)
+	}
+}
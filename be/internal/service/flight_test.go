@@ -0,0 +1,142 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// waitForWaiters blocks until n callers have joined the flight for key.
+func waitForWaiters(t *testing.T, g *flightGroup, key string, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		g.mu.Lock()
+		call := g.calls[key]
+		joined := call != nil && call.waiters >= n
+		g.mu.Unlock()
+		if joined {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d callers never joined %q", n, key)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestFlightLastCallerCancels(t *testing.T) {
+	var g flightGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan error, 1)
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Do(ctx, "hanoi", func(ctx context.Context) (interface{}, error) {
+			<-ctx.Done()
+			stopped <- ctx.Err()
+			return nil, ctx.Err()
+		})
+		done <- err
+	}()
+	waitForWaiters(t, &g, "hanoi", 1)
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("fetch stopped with %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("fetch was not cancelled")
+	}
+}
+
+func TestFlightDeadlineIsUpstreamUnavailable(t *testing.T) {
+	var g flightGroup
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := g.Do(ctx, "hanoi", func(ctx context.Context) (interface{}, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	if !errors.Is(err, util.ErrUpstreamUnavailable) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Do = %v, want upstream unavailable caused by the deadline", err)
+	}
+}
+
+func TestFlightSurvivesOneOfTwoWaiters(t *testing.T) {
+	provider := &fakeProvider{release: make(chan struct{})}
+	repo := store.NewInMemoryRepository()
+	svc := NewDefaultWeatherService(repo, provider, time.Minute)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	gone := make(chan error, 1)
+	go func() {
+		_, err := svc.GetWeatherDetails(ctx, "Hanoi")
+		gone <- err
+	}()
+	waitForWaiters(t, &svc.flight, "hanoi", 1)
+	stays := make(chan error, 1)
+	go func() {
+		_, err := svc.GetWeatherDetails(context.Background(), "Hanoi")
+		stays <- err
+	}()
+	waitForWaiters(t, &svc.flight, "hanoi", 2)
+
+	cancel()
+	if err := <-gone; !errors.Is(err, context.Canceled) {
+		t.Errorf("cancelled caller got %v, want %v", err, context.Canceled)
+	}
+	close(provider.release)
+	if err := <-stays; err != nil {
+		t.Errorf("remaining caller got %v, want the fetched weather", err)
+	}
+	if n := provider.calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+	if _, ok := repo.Get(context.Background(), "hanoi"); !ok {
+		t.Error("fetched weather was not cached")
+	}
+}
+
+func TestFlightCancelsUpstreamRequest(t *testing.T) {
+	arrived, seen := make(chan struct{}), make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(arrived)
+		<-r.Context().Done()
+		close(seen)
+	}))
+	defer srv.Close()
+	upstream := NewUpstreamClient(srv.Client(), UpstreamOptions{})
+
+	var g flightGroup
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := g.Do(ctx, "hanoi", func(ctx context.Context) (interface{}, error) {
+			var out struct{}
+			return nil, upstream.GetJSON(ctx, srv.URL, &out)
+		})
+		done <- err
+	}()
+	<-arrived
+	cancel()
+
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Do = %v, want %v", err, context.Canceled)
+	}
+	select {
+	case <-seen:
+	case <-time.After(2 * time.Second):
+		t.Fatal("upstream handler never saw the request cancelled")
+	}
+}
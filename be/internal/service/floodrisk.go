@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// FloodRiskClient reads flood risk from an external API that answers
+// GET <url>?latitude=..&longitude=.. with {"flood_risk": ..., "probability": ...},
+// such as cmd/floodstub. It implements flood.RiskAPI.
+type FloodRiskClient struct {
+	url      string
+	upstream *UpstreamClient
+}
+
+// NewFloodRiskClient builds a client for the risk endpoint at riskURL. A nil
+// upstream falls back to a default UpstreamClient.
+func NewFloodRiskClient(riskURL string, upstream *UpstreamClient) *FloodRiskClient {
+	if upstream == nil {
+		upstream = NewUpstreamClient(nil, UpstreamOptions{})
+	}
+	return &FloodRiskClient{url: riskURL, upstream: upstream}
+}
+
+// Risk returns the level and probability the API reports for the point.
+func (c *FloodRiskClient) Risk(ctx context.Context, lat, lon float64) (_ string, _ float64, err error) {
+	ctx, span := tracing.Start(ctx, "flood.risk_api", attribute.Float64("lat", lat), attribute.Float64("lon", lon))
+	defer func() { tracing.End(span, err) }()
+	riskURL, err := withQuery(c.url, url.Values{
+		"latitude":  {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"longitude": {strconv.FormatFloat(lon, 'f', -1, 64)},
+	})
+	if err != nil {
+		return "", 0, err
+	}
+	var body struct {
+		FloodRisk   string   `json:"flood_risk"`
+		Probability *float64 `json:"probability"`
+	}
+	if err := c.upstream.GetJSON(ctx, riskURL, &body); err != nil {
+		return "", 0, fmt.Errorf("failed to get flood risk: %w", err)
+	}
+	if body.FloodRisk == "" || body.Probability == nil {
+		return "", 0, fmt.Errorf("flood risk response lacks flood_risk or probability")
+	}
+	return body.FloodRisk, *body.Probability, nil
+}
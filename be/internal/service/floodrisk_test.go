@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+func TestFloodRiskClient(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		body    string
+		level   string
+		prob    float64
+		wantErr error
+	}{
+		{"answer", http.StatusOK, `{"flood_risk":"high","probability":0.82}`, "high", 0.82, nil},
+		{"zero probability", http.StatusOK, `{"flood_risk":"low","probability":0}`, "low", 0, nil},
+		{"missing probability", http.StatusOK, `{"flood_risk":"low"}`, "", 0, nil},
+		{"missing level", http.StatusOK, `{"probability":0.3}`, "", 0, nil},
+		{"outage", http.StatusServiceUnavailable, ``, "", 0, util.ErrUpstreamUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var query string
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				query = r.URL.RawQuery
+				if tt.status != http.StatusOK {
+					http.Error(w, "down", tt.status)
+					return
+				}
+				fmt.Fprint(w, tt.body)
+			}))
+			defer srv.Close()
+			c := NewFloodRiskClient(srv.URL+"/risk", NewUpstreamClient(srv.Client(), UpstreamOptions{}))
+
+			level, prob, err := c.Risk(context.Background(), 10.8231, 106.6297)
+			if query != "latitude=10.8231&longitude=106.6297" {
+				t.Errorf("query %q, want the coordinates", query)
+			}
+			if tt.level == "" {
+				if err == nil {
+					t.Fatalf("Risk = %s/%v, want an error", level, prob)
+				}
+				if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+					t.Errorf("Risk error %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil || level != tt.level || prob != tt.prob {
+				t.Errorf("Risk = %s/%v/%v, want %s/%v", level, prob, err, tt.level, tt.prob)
+			}
+		})
+	}
+}
@@ -1,22 +1,30 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"time"
 
 	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
 )
 
 type GeocodeService struct {
-	repo     store.WeatherRepository
-	cacheTTL time.Duration
+	repo       store.WeatherRepository
+	cacheTTL   time.Duration
+	geocodeURL string
+	upstream   *UpstreamClient
 }
 
-func NewGeocodeService(repo store.WeatherRepository, cacheTTL time.Duration) *GeocodeService {
-	return &GeocodeService{repo: repo, cacheTTL: cacheTTL}
+// NewGeocodeService builds a city search service against the given geocoding
+// endpoint. A nil upstream falls back to a default UpstreamClient.
+func NewGeocodeService(repo store.WeatherRepository, cacheTTL time.Duration, geocodeURL string, upstream *UpstreamClient) *GeocodeService {
+	if upstream == nil {
+		upstream = NewUpstreamClient(nil, UpstreamOptions{})
+	}
+	return &GeocodeService{repo: repo, cacheTTL: cacheTTL, geocodeURL: geocodeURL, upstream: upstream}
 }
 
 type CitySuggestion struct {
@@ -26,14 +34,20 @@ type CitySuggestion struct {
 	Lon     float64 `json:"lon"`
 }
 
-func (g *GeocodeService) SearchCity(query string) ([]CitySuggestion, error) {
+func (g *GeocodeService) SearchCity(ctx context.Context, query string) (_ []CitySuggestion, err error) {
+	ctx, span := tracing.Start(ctx, "geocode.search", tracing.AttrCity.String(query))
+	defer func() { tracing.End(span, err) }()
+	ctx = util.WithLogAttrs(ctx, "city", query)
 	// Optionally cache city search results
-	geoURL := fmt.Sprintf("https://geocoding-api.open-meteo.com/v1/search?name=%s&count=5&language=en&format=json", url.QueryEscape(query))
-	resp, err := http.Get(geoURL)
+	geoURL, err := withQuery(g.geocodeURL, url.Values{
+		"name":     {query},
+		"count":    {"5"},
+		"language": {"en"},
+		"format":   {"json"},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to geocode city: %w", err)
+		return nil, err
 	}
-	defer resp.Body.Close()
 	var geo struct {
 		Results []struct {
 			Name      string  `json:"name"`
@@ -42,11 +56,11 @@ func (g *GeocodeService) SearchCity(query string) ([]CitySuggestion, error) {
 			Longitude float64 `json:"longitude"`
 		} `json:"results"`
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&geo); err != nil {
-		return nil, fmt.Errorf("invalid geocoding response: %w", err)
+	if err := g.upstream.GetJSON(ctx, geoURL, &geo); err != nil {
+		return nil, fmt.Errorf("failed to geocode city: %w", err)
 	}
 	if len(geo.Results) == 0 {
-		return nil, fmt.Errorf("no results found")
+		return nil, util.NotFound("no results found")
 	}
 	results := make([]CitySuggestion, 0, len(geo.Results))
 	for _, r := range geo.Results {
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+const (
+	openMeteoHourlyVars = "temperature_2m,apparent_temperature,relative_humidity_2m,precipitation_probability,precipitation,rain,snowfall,snow_depth,cloudcover,uv_index,visibility,surface_pressure,windspeed_10m,winddirection_10m"
+	openMeteoDailyVars  = "temperature_2m_min,temperature_2m_max,precipitation_sum,uv_index_max,sunrise,sunset"
+	// Open-Meteo returns local times without an offset when timezone=auto.
+	openMeteoTimeLayout = "2006-01-02T15:04"
+	openMeteoDateLayout = "2006-01-02"
+)
+
+// OpenMeteoProvider implements WeatherProvider on top of the Open-Meteo
+// geocoding and forecast APIs.
+type OpenMeteoProvider struct {
+	geocodeURL  string
+	forecastURL string
+	upstream    *UpstreamClient
+}
+
+// NewOpenMeteoProvider builds a provider against the given geocoding and
+// forecast endpoints, which may point at a mirror or a local fake. A nil
+// upstream falls back to a default UpstreamClient.
+func NewOpenMeteoProvider(geocodeURL, forecastURL string, upstream *UpstreamClient) *OpenMeteoProvider {
+	if upstream == nil {
+		upstream = NewUpstreamClient(nil, UpstreamOptions{})
+	}
+	return &OpenMeteoProvider{geocodeURL: geocodeURL, forecastURL: forecastURL, upstream: upstream}
+}
+
+// Geocode returns the top Open-Meteo geocoding match for city.
+func (p *OpenMeteoProvider) Geocode(ctx context.Context, city string) (_ model.City, err error) {
+	ctx, span := tracing.Start(ctx, "geocode", tracing.AttrCity.String(city))
+	defer func() { tracing.End(span, err) }()
+	geoURL, err := withQuery(p.geocodeURL, url.Values{
+		"name":     {city},
+		"count":    {"1"},
+		"language": {"en"},
+		"format":   {"json"},
+	})
+	if err != nil {
+		return model.City{}, err
+	}
+	var geo struct {
+		Results []struct {
+			Name      string  `json:"name"`
+			Latitude  float64 `json:"latitude"`
+			Longitude float64 `json:"longitude"`
+			Country   string  `json:"country"`
+		} `json:"results"`
+	}
+	if err := p.upstream.GetJSON(ctx, geoURL, &geo); err != nil {
+		return model.City{}, fmt.Errorf("failed to geocode city: %w", err)
+	}
+	if len(geo.Results) == 0 {
+		return model.City{}, util.NotFound("city not found")
+	}
+	r := geo.Results[0]
+	return model.City{Name: r.Name, Country: r.Country, Lat: r.Latitude, Lon: r.Longitude}, nil
+}
+
+// openMeteoResponse is the subset of the /v1/forecast payload we read.
+type openMeteoResponse struct {
+	Timezone         string `json:"timezone"`
+	UTCOffsetSeconds int    `json:"utc_offset_seconds"`
+	CurrentWeather   struct {
+		Temperature float64 `json:"temperature"`
+		WindSpeed   float64 `json:"windspeed"`
+		WindDir     float64 `json:"winddirection"`
+		WeatherCode int     `json:"weathercode"`
+		Time        string  `json:"time"`
+	} `json:"current_weather"`
+	Hourly struct {
+		Time                []string  `json:"time"`
+		Temperature2m       []float64 `json:"temperature_2m"`
+		ApparentTemperature []float64 `json:"apparent_temperature"`
+		RelativeHumidity2m  []float64 `json:"relative_humidity_2m"`
+		PrecipitationProb   []float64 `json:"precipitation_probability"`
+		Precipitation       []float64 `json:"precipitation"`
+		Rain                []float64 `json:"rain"`
+		Snowfall            []float64 `json:"snowfall"`
+		SnowDepth           []float64 `json:"snow_depth"`
+		CloudCover          []float64 `json:"cloudcover"`
+		UVIndex             []float64 `json:"uv_index"`
+		Visibility          []float64 `json:"visibility"`
+		SurfacePressure     []float64 `json:"surface_pressure"`
+		WindSpeed10m        []float64 `json:"windspeed_10m"`
+		WindDirection10m    []float64 `json:"winddirection_10m"`
+	} `json:"hourly"`
+	Daily struct {
+		Time             []string  `json:"time"`
+		Temperature2mMin []float64 `json:"temperature_2m_min"`
+		Temperature2mMax []float64 `json:"temperature_2m_max"`
+		PrecipitationSum []float64 `json:"precipitation_sum"`
+		UVIndexMax       []float64 `json:"uv_index_max"`
+		Sunrise          []string  `json:"sunrise"`
+		Sunset           []string  `json:"sunset"`
+	} `json:"daily"`
+}
+
+// location returns the fixed zone Open-Meteo used for the local timestamps.
+func (w *openMeteoResponse) location() *time.Location {
+	return time.FixedZone(w.Timezone, w.UTCOffsetSeconds)
+}
+
+// fetchForecast calls the forecast endpoint for the coordinates with the
+// extra parameters merged in.
+func (p *OpenMeteoProvider) fetchForecast(ctx context.Context, lat, lon float64, extra url.Values) (openMeteoResponse, error) {
+	params := url.Values{
+		"latitude":  {fmt.Sprintf("%.4f", lat)},
+		"longitude": {fmt.Sprintf("%.4f", lon)},
+		"timezone":  {"auto"},
+	}
+	for k, v := range extra {
+		params[k] = v
+	}
+	weatherURL, err := withQuery(p.forecastURL, params)
+	if err != nil {
+		return openMeteoResponse{}, err
+	}
+	var wres openMeteoResponse
+	if err := p.upstream.GetJSON(ctx, weatherURL, &wres); err != nil {
+		return openMeteoResponse{}, fmt.Errorf("failed to fetch weather: %w", err)
+	}
+	return wres, nil
+}
+
+// Current fetches the forecast for the coordinates and picks out the values
+// for the current hour.
+func (p *OpenMeteoProvider) Current(ctx context.Context, lat, lon float64) (_ model.WeatherDetails, err error) {
+	ctx, span := tracing.Start(ctx, "forecast.current", attribute.Float64("lat", lat), attribute.Float64("lon", lon))
+	defer func() { tracing.End(span, err) }()
+	wres, err := p.fetchForecast(ctx, lat, lon, url.Values{
+		"current_weather": {"true"},
+		"hourly":          {openMeteoHourlyVars},
+		"daily":           {"sunrise,sunset"},
+	})
+	if err != nil {
+		return model.WeatherDetails{}, err
+	}
+
+	// Find the index for the current hour
+	idx := 0
+	for i, t := range wres.Hourly.Time {
+		if t == wres.CurrentWeather.Time {
+			idx = i
+			break
+		}
+	}
+
+	loc := wres.location()
+	return model.WeatherDetails{
+		Temperature: wres.CurrentWeather.Temperature,
+		FeelsLike:   valueAt(wres.Hourly.ApparentTemperature, idx),
+		Humidity:    int(valueAt(wres.Hourly.RelativeHumidity2m, idx)),
+		WindSpeed:   wres.CurrentWeather.WindSpeed,
+		WindDir:     fmt.Sprintf("%.0f°", wres.CurrentWeather.WindDir),
+		Visibility:  valueAt(wres.Hourly.Visibility, idx) / 1000.0,
+		Pressure:    int(valueAt(wres.Hourly.SurfacePressure, idx)),
+		UVIndex:     int(valueAt(wres.Hourly.UVIndex, idx)),
+		Sunrise:     parseLocalTime(wres.Daily.Sunrise, 0, loc),
+		Sunset:      parseLocalTime(wres.Daily.Sunset, 0, loc),
+		CloudCover:  int(valueAt(wres.Hourly.CloudCover, idx)),
+		PrecipProb:  valueAt(wres.Hourly.PrecipitationProb, idx) / 100.0,
+		Rain:        valueAt(wres.Hourly.Rain, idx),
+		Snow:        valueAt(wres.Hourly.Snowfall, idx),
+		UpdatedAt:   time.Now(),
+	}, nil
+}
+
+// Forecast fetches the hourly series and daily aggregates covering pastDays
+// of recent observations followed by the next days.
+func (p *OpenMeteoProvider) Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (_ model.Forecast, err error) {
+	ctx, span := tracing.Start(ctx, "forecast.fetch",
+		attribute.Float64("lat", lat), attribute.Float64("lon", lon),
+		attribute.Int("past_days", pastDays), attribute.Int("days", days))
+	defer func() { tracing.End(span, err) }()
+	wres, err := p.fetchForecast(ctx, lat, lon, url.Values{
+		"hourly":        {openMeteoHourlyVars},
+		"daily":         {openMeteoDailyVars},
+		"past_days":     {strconv.Itoa(pastDays)},
+		"forecast_days": {strconv.Itoa(days)},
+	})
+	if err != nil {
+		return model.Forecast{}, err
+	}
+
+	loc := wres.location()
+	fc := model.Forecast{
+		Lat:       lat,
+		Lon:       lon,
+		Timezone:  wres.Timezone,
+		Hourly:    make([]model.HourlyForecast, 0, len(wres.Hourly.Time)),
+		Daily:     make([]model.DailyForecast, 0, len(wres.Daily.Time)),
+		UpdatedAt: time.Now(),
+	}
+	h := wres.Hourly
+	for i := range h.Time {
+		fc.Hourly = append(fc.Hourly, model.HourlyForecast{
+			Time:          parseLocalTime(h.Time, i, loc),
+			Temperature:   valueAt(h.Temperature2m, i),
+			FeelsLike:     valueAt(h.ApparentTemperature, i),
+			Humidity:      int(valueAt(h.RelativeHumidity2m, i)),
+			WindSpeed:     valueAt(h.WindSpeed10m, i),
+			WindDir:       fmt.Sprintf("%.0f°", valueAt(h.WindDirection10m, i)),
+			Visibility:    valueAt(h.Visibility, i) / 1000.0,
+			Pressure:      int(valueAt(h.SurfacePressure, i)),
+			UVIndex:       valueAt(h.UVIndex, i),
+			CloudCover:    int(valueAt(h.CloudCover, i)),
+			PrecipProb:    valueAt(h.PrecipitationProb, i) / 100.0,
+			Precipitation: valueAt(h.Precipitation, i),
+			Rain:          valueAt(h.Rain, i),
+			Snow:          valueAt(h.Snowfall, i),
+			SnowDepth:     valueAt(h.SnowDepth, i),
+		})
+	}
+	d := wres.Daily
+	for i := range d.Time {
+		date, _ := time.ParseInLocation(openMeteoDateLayout, d.Time[i], loc)
+		fc.Daily = append(fc.Daily, model.DailyForecast{
+			Date:       date,
+			TempMin:    valueAt(d.Temperature2mMin, i),
+			TempMax:    valueAt(d.Temperature2mMax, i),
+			PrecipSum:  valueAt(d.PrecipitationSum, i),
+			UVIndexMax: valueAt(d.UVIndexMax, i),
+			Sunrise:    parseLocalTime(d.Sunrise, i, loc),
+			Sunset:     parseLocalTime(d.Sunset, i, loc),
+		})
+	}
+	return fc, nil
+}
+
+// valueAt returns vals[i], or 0 when Open-Meteo sent a shorter series.
+func valueAt(vals []float64, i int) float64 {
+	if i < 0 || i >= len(vals) {
+		return 0
+	}
+	return vals[i]
+}
+
+// parseLocalTime parses the i-th Open-Meteo local timestamp in loc. Missing
+// or malformed entries yield the zero time.
+func parseLocalTime(vals []string, i int, loc *time.Location) time.Time {
+	if i < 0 || i >= len(vals) {
+		return time.Time{}
+	}
+	t, err := time.ParseInLocation(openMeteoTimeLayout, vals[i], loc)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// withQuery merges params into the query string of an endpoint URL taken
+// from configuration, keeping any parameters the endpoint already carries.
+func withQuery(endpoint string, params url.Values) (string, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil {
+		return "", fmt.Errorf("invalid upstream url %q: %w", endpoint, err)
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), nil
+}
@@ -0,0 +1,84 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/cassette"
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// newReplayProvider points an OpenMeteoProvider at the real Open-Meteo
+// endpoints, answered from testdata/cassettes without touching the network.
+func newReplayProvider(t *testing.T) *OpenMeteoProvider {
+	t.Helper()
+	rec, err := cassette.New(cassette.ModeReplay, filepath.Join("testdata", "cassettes"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewOpenMeteoProvider(
+		"https://geocoding-api.open-meteo.com/v1/search",
+		"https://api.open-meteo.com/v1/forecast",
+		NewUpstreamClient(&http.Client{Transport: rec}, UpstreamOptions{}),
+	)
+}
+
+// checkGolden compares got, as indented JSON, with testdata/name, or
+// rewrites the file when -update is set.
+func checkGolden(t *testing.T, name string, got interface{}) {
+	t.Helper()
+	raw, err := json.MarshalIndent(got, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw = append(raw, '\n')
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, raw, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(raw, want) {
+		t.Errorf("%s differs from the golden file:\ngot:\n%s\nwant:\n%s", name, raw, want)
+	}
+}
+
+func TestOpenMeteoCurrentGolden(t *testing.T) {
+	p := newReplayProvider(t)
+	ctx := context.Background()
+
+	loc, err := p.Geocode(ctx, "Hanoi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	details, err := p.Current(ctx, loc.Lat, loc.Lon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	details.UpdatedAt = time.Time{}
+
+	// The cassette's current_weather.time is the third hourly entry, whose
+	// visibility is 24140 m and precipitation probability 35%.
+	if details.Visibility != 24.14 || details.PrecipProb != 0.35 || details.Humidity != 63 {
+		t.Errorf("visibility %v km, precipitation %v, humidity %d%%; want the current hour's 24.14, 0.35 and 63",
+			details.Visibility, details.PrecipProb, details.Humidity)
+	}
+	checkGolden(t, "openmeteo_current.golden.json", struct {
+		City    model.City           `json:"city"`
+		Current model.WeatherDetails `json:"current"`
+	}{loc, details})
+}
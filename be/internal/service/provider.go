@@ -0,0 +1,24 @@
+package service
+
+import (
+	"context"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+)
+
+// WeatherProvider is an upstream source of geocoding and weather data.
+// DefaultWeatherService only talks to upstreams through this interface, so
+// tests can substitute a fake and other providers can be added alongside
+// Open-Meteo. ctx carries the request's deadline and logger into the
+// upstream call.
+type WeatherProvider interface {
+	// Geocode resolves a city name to its best match.
+	Geocode(ctx context.Context, city string) (model.City, error)
+	// Current returns normalized current conditions at the given coordinates.
+	// The City field is left for the caller to fill in.
+	Current(ctx context.Context, lat, lon float64) (model.WeatherDetails, error)
+	// Forecast returns the hourly series and daily aggregates from pastDays
+	// ago through the next days, starting today. The City field is left for
+	// the caller to fill in.
+	Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (model.Forecast, error)
+}
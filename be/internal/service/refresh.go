@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"sync"
+)
+
+// refreshPool runs background cache refreshes on a fixed number of workers.
+// A refresh for a key that is already queued or running is dropped, as is
+// any refresh submitted while the queue is full, so a burst of stale hits
+// can never fan out into unbounded upstream traffic.
+type refreshPool struct {
+	jobs    chan refreshJob
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+	pending map[string]struct{}
+	closed  bool
+	// abort is cancelled when a shutdown deadline passes, stopping the
+	// refreshes still running or queued.
+	abort  context.Context
+	cancel context.CancelFunc
+}
+
+type refreshJob struct {
+	key string
+	ctx context.Context
+	fn  func(ctx context.Context)
+}
+
+func newRefreshPool(workers, queueSize int) *refreshPool {
+	if workers < 1 {
+		workers = 1
+	}
+	if queueSize < workers {
+		queueSize = workers
+	}
+	p := &refreshPool{
+		jobs:    make(chan refreshJob, queueSize),
+		pending: make(map[string]struct{}),
+	}
+	p.abort, p.cancel = context.WithCancel(context.Background())
+	for i := 0; i < workers; i++ {
+		p.wg.Add(1)
+		go p.work()
+	}
+	return p
+}
+
+func (p *refreshPool) work() {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		p.run(job)
+		p.mu.Lock()
+		delete(p.pending, job.key)
+		p.mu.Unlock()
+	}
+}
+
+func (p *refreshPool) run(job refreshJob) {
+	// Refreshes still queued when the pool is aborted are dropped.
+	if p.abort.Err() != nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(job.ctx)
+	defer cancel()
+	defer context.AfterFunc(p.abort, cancel)()
+	job.fn(ctx)
+}
+
+// submit queues fn to refresh key and reports whether it was accepted. fn
+// runs with ctx, which is also cancelled if the pool is aborted.
+func (p *refreshPool) submit(ctx context.Context, key string, fn func(ctx context.Context)) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return false
+	}
+	if _, ok := p.pending[key]; ok {
+		return false
+	}
+	select {
+	case p.jobs <- refreshJob{key: key, ctx: ctx, fn: fn}:
+		p.pending[key] = struct{}{}
+		return true
+	default:
+		return false
+	}
+}
+
+// close stops accepting work and waits for queued refreshes to finish. If
+// ctx ends first, the remaining refreshes are cancelled and close returns
+// ctx's error once the workers have stopped.
+func (p *refreshPool) close(ctx context.Context) error {
+	p.mu.Lock()
+	if !p.closed {
+		p.closed = true
+		close(p.jobs)
+	}
+	p.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		p.cancel()
+		<-done
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// blockingJob returns a refresh that signals started and then waits for
+// release or cancellation, recording the error it stopped with.
+func blockingJob(started chan<- struct{}, release <-chan struct{}, stopped chan<- error) func(ctx context.Context) {
+	return func(ctx context.Context) {
+		started <- struct{}{}
+		select {
+		case <-release:
+			stopped <- nil
+		case <-ctx.Done():
+			stopped <- ctx.Err()
+		}
+	}
+}
+
+func TestRefreshPoolDropsWhenFull(t *testing.T) {
+	p := newRefreshPool(1, 1)
+	started, release, stopped := make(chan struct{}, 1), make(chan struct{}), make(chan error, 1)
+	var ran atomic.Int32
+	count := func(context.Context) { ran.Add(1) }
+
+	if !p.submit(context.Background(), "hanoi", blockingJob(started, release, stopped)) {
+		t.Fatal("first refresh was rejected")
+	}
+	<-started
+	if !p.submit(context.Background(), "oslo", count) {
+		t.Fatal("refresh into an empty queue was rejected")
+	}
+	if p.submit(context.Background(), "paris", count) {
+		t.Error("refresh into a full queue was accepted")
+	}
+	close(release)
+	if err := p.close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := ran.Load(); n != 1 {
+		t.Errorf("%d queued refreshes ran, want 1", n)
+	}
+	if p.submit(context.Background(), "rome", count) {
+		t.Error("refresh accepted after close")
+	}
+}
+
+func TestRefreshPoolDedupsPendingKey(t *testing.T) {
+	p := newRefreshPool(1, 4)
+	started, release, stopped := make(chan struct{}, 1), make(chan struct{}), make(chan error, 1)
+	var ran atomic.Int32
+	count := func(context.Context) { ran.Add(1) }
+
+	p.submit(context.Background(), "hanoi", blockingJob(started, release, stopped))
+	<-started
+	if p.submit(context.Background(), "hanoi", count) {
+		t.Error("refresh of a running key was accepted")
+	}
+	if !p.submit(context.Background(), "oslo", count) {
+		t.Fatal("refresh of another key was rejected")
+	}
+	if p.submit(context.Background(), "oslo", count) {
+		t.Error("refresh of a queued key was accepted")
+	}
+	close(release)
+	<-stopped
+	// Once its refresh has finished the key can be queued again.
+	deadline := time.Now().Add(time.Second)
+	for !p.submit(context.Background(), "hanoi", count) {
+		if time.Now().After(deadline) {
+			t.Fatal("key still pending after its refresh finished")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := p.close(context.Background()); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if n := ran.Load(); n != 2 {
+		t.Errorf("%d refreshes ran, want 2", n)
+	}
+}
+
+func TestRefreshPoolCloseCancelsAtDeadline(t *testing.T) {
+	p := newRefreshPool(1, 4)
+	started, stopped := make(chan struct{}, 1), make(chan error, 1)
+	var ran atomic.Int32
+
+	p.submit(context.Background(), "hanoi", blockingJob(started, nil, stopped))
+	<-started
+	p.submit(context.Background(), "oslo", func(context.Context) { ran.Add(1) })
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := p.close(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("close = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if err := <-stopped; !errors.Is(err, context.Canceled) {
+		t.Errorf("running refresh stopped with %v, want %v", err, context.Canceled)
+	}
+	if n := ran.Load(); n != 0 {
+		t.Errorf("%d queued refreshes ran after the deadline, want 0", n)
+	}
+}
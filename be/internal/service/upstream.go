@@ -0,0 +1,366 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/metrics"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// ErrCircuitOpen is returned without calling upstream while a host's circuit
+// breaker is open.
+var ErrCircuitOpen = errors.New("upstream circuit open")
+
+var errInvalidBody = errors.New("invalid response")
+
+// UpstreamError describes a non-2xx response from an upstream API.
+type UpstreamError struct {
+	Host       string
+	StatusCode int
+	Body       string
+}
+
+func (e *UpstreamError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("upstream %s returned %d", e.Host, e.StatusCode)
+	}
+	return fmt.Sprintf("upstream %s returned %d: %s", e.Host, e.StatusCode, e.Body)
+}
+
+// retryable reports whether the status is worth another attempt.
+func (e *UpstreamError) retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= 500
+}
+
+// UpstreamOptions tunes an UpstreamClient. Zero values pick the defaults.
+type UpstreamOptions struct {
+	Timeout          time.Duration // per attempt (default 10s)
+	MaxRetries       int           // extra attempts after the first (default 0)
+	BaseBackoff      time.Duration // first retry delay before jitter (default 200ms)
+	BreakerThreshold int           // consecutive failures that open a breaker (default 5)
+	BreakerCooldown  time.Duration // how long a breaker stays open (default 30s)
+}
+
+// UpstreamClient is the shared HTTP layer for calls to external APIs. Every
+// attempt runs under its own deadline and has its status code checked;
+// failed GETs are retried with jittered exponential backoff, and each host
+// has a circuit breaker so a failing upstream is not hammered.
+type UpstreamClient struct {
+	client *http.Client
+	opts   UpstreamOptions
+	// now reads the clock for the circuit breakers.
+	now func() time.Time
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+// NewUpstreamClient wraps client, which may be nil for http.DefaultClient.
+func NewUpstreamClient(client *http.Client, opts UpstreamOptions) *UpstreamClient {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	if opts.Timeout <= 0 {
+		opts.Timeout = 10 * time.Second
+	}
+	if opts.MaxRetries < 0 {
+		opts.MaxRetries = 0
+	}
+	if opts.BaseBackoff <= 0 {
+		opts.BaseBackoff = 200 * time.Millisecond
+	}
+	if opts.BreakerThreshold <= 0 {
+		opts.BreakerThreshold = 5
+	}
+	if opts.BreakerCooldown <= 0 {
+		opts.BreakerCooldown = 30 * time.Second
+	}
+	return &UpstreamClient{client: client, opts: opts, now: time.Now, breakers: make(map[string]*circuitBreaker)}
+}
+
+// GetJSON fetches rawURL and decodes a 2xx JSON body into out, logging the
+// call through ctx's logger. Failures are
+// reported as util error kinds: 429 as RateLimited, and an open breaker,
+// network errors, timeouts, 5xx and undecodable bodies as
+// UpstreamUnavailable.
+func (u *UpstreamClient) GetJSON(ctx context.Context, rawURL string, out interface{}) error {
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid upstream url %q: %w", rawURL, err)
+	}
+	host := parsed.Host
+	start := time.Now()
+	err = u.getJSON(ctx, rawURL, host, out)
+	log := util.Log(ctx).With("host", host, "path", parsed.Path, "duration_ms", time.Since(start).Milliseconds())
+	if err == nil {
+		log.Info("upstream call")
+		return nil
+	}
+	log.Warn("upstream call failed", "err", err)
+	var ue *UpstreamError
+	switch {
+	case errors.As(err, &ue) && ue.StatusCode == http.StatusTooManyRequests:
+		return util.RateLimited("upstream rate limit exceeded", err)
+	case errors.As(err, &ue) && !ue.retryable():
+		return err
+	default:
+		return util.UpstreamUnavailable("upstream weather service unavailable", err)
+	}
+}
+
+func (u *UpstreamClient) getJSON(ctx context.Context, rawURL, host string, out interface{}) error {
+	br := u.breaker(host)
+	if !br.allow(u.now()) {
+		metrics.UpstreamError(host, "circuit_open")
+		return fmt.Errorf("%s: %w", host, ErrCircuitOpen)
+	}
+
+	var lastErr error
+	for attempt := 0; attempt <= u.opts.MaxRetries; attempt++ {
+		if attempt > 0 {
+			if err := sleepCtx(ctx, u.backoff(attempt)); err != nil {
+				lastErr = err
+				break
+			}
+		}
+		start := time.Now()
+		lastErr = u.getOnce(ctx, rawURL, host, out)
+		metrics.UpstreamAttempt(host, time.Since(start))
+		if lastErr == nil {
+			br.success()
+			return nil
+		}
+		metrics.UpstreamError(host, errorReason(lastErr))
+		var ue *UpstreamError
+		if errors.As(lastErr, &ue) && !ue.retryable() {
+			break
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	var ue *UpstreamError
+	switch {
+	case errors.As(lastErr, &ue) && !ue.retryable():
+		// The host answered; a 4xx other than 429 is our request's fault.
+		br.success()
+	case ctx.Err() != nil:
+		// The caller gave up, which says nothing about the host.
+		br.release()
+	default:
+		br.failure(u.now())
+	}
+	return lastErr
+}
+
+func (u *UpstreamClient) getOnce(ctx context.Context, rawURL, host string, out interface{}) error {
+	ctx, cancel := context.WithTimeout(ctx, u.opts.Timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	tracing.Inject(ctx, req.Header)
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &UpstreamError{Host: host, StatusCode: resp.StatusCode, Body: string(body)}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("%w from %s: %v", errInvalidBody, host, err)
+	}
+	return nil
+}
+
+// errorReason classifies a failed attempt for the upstream error metric.
+func errorReason(err error) string {
+	var ue *UpstreamError
+	switch {
+	case errors.As(err, &ue) && ue.StatusCode == http.StatusTooManyRequests:
+		return "rate_limited"
+	case errors.As(err, &ue) && ue.StatusCode >= 500:
+		return "http_5xx"
+	case errors.As(err, &ue):
+		return "http_4xx"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, errInvalidBody):
+		return "invalid_body"
+	default:
+		return "network"
+	}
+}
+
+// backoff returns a full-jitter delay for the given retry attempt (1-based).
+func (u *UpstreamClient) backoff(attempt int) time.Duration {
+	ceiling := u.opts.BaseBackoff << (attempt - 1)
+	return u.opts.BaseBackoff/2 + time.Duration(rand.Int63n(int64(ceiling)))
+}
+
+func (u *UpstreamClient) breaker(host string) *circuitBreaker {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	br, ok := u.breakers[host]
+	if !ok {
+		br = &circuitBreaker{threshold: u.opts.BreakerThreshold, cooldown: u.opts.BreakerCooldown}
+		u.breakers[host] = br
+	}
+	return br
+}
+
+// BreakerStatus is the externally visible state of one host's breaker.
+type BreakerStatus struct {
+	Host                string     `json:"host"`
+	State               string     `json:"state"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	OpenUntil           *time.Time `json:"openUntil,omitempty"`
+}
+
+// BreakerStates reports the circuit breaker of every host called so far.
+func (u *UpstreamClient) BreakerStates() []BreakerStatus {
+	u.mu.Lock()
+	hosts := make([]string, 0, len(u.breakers))
+	for h := range u.breakers {
+		hosts = append(hosts, h)
+	}
+	u.mu.Unlock()
+	sort.Strings(hosts)
+	out := make([]BreakerStatus, 0, len(hosts))
+	now := u.now()
+	for _, h := range hosts {
+		out = append(out, u.breaker(h).status(h, now))
+	}
+	return out
+}
+
+// ProbeResult reports whether an upstream endpoint answered a probe.
+type ProbeResult struct {
+	URL        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"statusCode,omitempty"`
+	LatencyMS  int64  `json:"latencyMs"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Probe sends a single GET to rawURL, bypassing retries and the circuit
+// breaker. Any HTTP response counts as reachable: the endpoint may reject a
+// bare request while still being up.
+func (u *UpstreamClient) Probe(ctx context.Context, rawURL string) ProbeResult {
+	res := ProbeResult{URL: rawURL}
+	ctx, cancel := context.WithTimeout(ctx, u.opts.Timeout)
+	defer cancel()
+	start := time.Now()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	resp, err := u.client.Do(req)
+	res.LatencyMS = time.Since(start).Milliseconds()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	resp.Body.Close()
+	res.Reachable, res.StatusCode = true, resp.StatusCode
+	return res
+}
+
+const (
+	breakerClosed   = "closed"
+	breakerOpen     = "open"
+	breakerHalfOpen = "half-open"
+)
+
+// circuitBreaker opens after threshold consecutive failures. Once the
+// cooldown has passed a single trial call is let through (half-open); its
+// outcome closes or re-opens the breaker.
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+	trial     bool
+}
+
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.failures < b.threshold {
+		return true
+	}
+	if now.Before(b.openUntil) || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.trial = false
+}
+
+// release gives up a half-open trial without recording an outcome.
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure(now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openUntil = now.Add(b.cooldown)
+	}
+}
+
+func (b *circuitBreaker) status(host string, now time.Time) BreakerStatus {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	st := BreakerStatus{Host: host, State: breakerClosed, ConsecutiveFailures: b.failures}
+	if b.failures >= b.threshold {
+		st.State = breakerHalfOpen
+		if now.Before(b.openUntil) {
+			st.State = breakerOpen
+			until := b.openUntil
+			st.OpenUntil = &until
+		}
+	}
+	return st
+}
+
+func sleepCtx(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
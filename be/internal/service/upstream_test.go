@@ -0,0 +1,228 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+)
+
+// scriptedServer answers with the given statuses in order and with 200 once
+// they run out. A 200 carries a small JSON body.
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	statuses []int
+	hits     int
+}
+
+func newScriptedServer(t *testing.T, statuses ...int) *scriptedServer {
+	t.Helper()
+	s := &scriptedServer{statuses: statuses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		status := http.StatusOK
+		if s.hits < len(s.statuses) {
+			status = s.statuses[s.hits]
+		}
+		s.hits++
+		s.mu.Unlock()
+		if status != http.StatusOK {
+			http.Error(w, "scripted failure", status)
+			return
+		}
+		fmt.Fprint(w, `{"ok":true}`)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *scriptedServer) hitCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.hits
+}
+
+// testClock is a settable clock for the circuit breakers.
+type testClock struct{ now time.Time }
+
+func (c *testClock) advance(d time.Duration) { c.now = c.now.Add(d) }
+
+// newTestUpstream returns a client for srv with near-zero backoff and its
+// breakers on clock.
+func newTestUpstream(srv *scriptedServer, opts UpstreamOptions, clock *testClock) *UpstreamClient {
+	opts.BaseBackoff = time.Nanosecond
+	u := NewUpstreamClient(srv.Client(), opts)
+	if clock != nil {
+		u.now = func() time.Time { return clock.now }
+	}
+	return u
+}
+
+type okBody struct {
+	OK bool `json:"ok"`
+}
+
+func TestUpstreamRetriesUntilSuccess(t *testing.T) {
+	srv := newScriptedServer(t, http.StatusServiceUnavailable, http.StatusBadGateway)
+	u := newTestUpstream(srv, UpstreamOptions{MaxRetries: 2}, nil)
+
+	var out okBody
+	if err := u.GetJSON(context.Background(), srv.URL, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !out.OK {
+		t.Error("body was not decoded")
+	}
+	if n := srv.hitCount(); n != 3 {
+		t.Errorf("upstream hit %d times, want 3", n)
+	}
+}
+
+func TestUpstreamStatusClassification(t *testing.T) {
+	tests := []struct {
+		name     string
+		statuses []int
+		kind     error // util error kind, nil for a plain *UpstreamError
+		status   int
+		hits     int
+	}{
+		{"429 is retried then rate limited", []int{429, 429}, util.ErrRateLimited, 429, 2},
+		{"5xx is retried then unavailable", []int{500, 503}, util.ErrUpstreamUnavailable, 503, 2},
+		{"4xx is not retried", []int{404}, nil, 404, 1},
+		{"recovery on the retry", []int{503}, nil, 0, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newScriptedServer(t, tt.statuses...)
+			u := newTestUpstream(srv, UpstreamOptions{MaxRetries: 1}, nil)
+
+			err := u.GetJSON(context.Background(), srv.URL, &okBody{})
+			if n := srv.hitCount(); n != tt.hits {
+				t.Errorf("upstream hit %d times, want %d", n, tt.hits)
+			}
+			if tt.status == 0 {
+				if err != nil {
+					t.Errorf("GetJSON = %v, want success", err)
+				}
+				return
+			}
+			var ue *UpstreamError
+			if !errors.As(err, &ue) || ue.StatusCode != tt.status {
+				t.Fatalf("GetJSON = %v, want an UpstreamError with status %d", err, tt.status)
+			}
+			for _, kind := range []error{util.ErrRateLimited, util.ErrUpstreamUnavailable} {
+				if got, want := errors.Is(err, kind), kind == tt.kind; got != want {
+					t.Errorf("errors.Is(err, %v) = %v, want %v", kind, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestUpstreamBreakerTransitions(t *testing.T) {
+	srv := newScriptedServer(t, 500, 500, 500)
+	clock := &testClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	const cooldown = time.Minute
+	u := newTestUpstream(srv, UpstreamOptions{BreakerThreshold: 2, BreakerCooldown: cooldown}, clock)
+	ctx := context.Background()
+	host := strings.TrimPrefix(srv.URL, "http://")
+
+	state := func(wantState string, wantFailures int) {
+		t.Helper()
+		states := u.BreakerStates()
+		if len(states) != 1 || states[0].Host != host {
+			t.Fatalf("BreakerStates = %+v, want one entry for %s", states, host)
+		}
+		if st := states[0]; st.State != wantState || st.ConsecutiveFailures != wantFailures {
+			t.Errorf("breaker %s with %d failures, want %s with %d", st.State, st.ConsecutiveFailures, wantState, wantFailures)
+		}
+	}
+	call := func(wantOpen bool, wantHits int) {
+		t.Helper()
+		err := u.GetJSON(ctx, srv.URL, &okBody{})
+		if got := errors.Is(err, ErrCircuitOpen); got != wantOpen {
+			t.Errorf("GetJSON = %v; circuit open %v, want %v", err, got, wantOpen)
+		}
+		if wantOpen && !errors.Is(err, util.ErrUpstreamUnavailable) {
+			t.Errorf("refusal %v is not UpstreamUnavailable", err)
+		}
+		if n := srv.hitCount(); n != wantHits {
+			t.Errorf("upstream hit %d times, want %d", n, wantHits)
+		}
+	}
+
+	call(false, 1)
+	state(breakerClosed, 1)
+	call(false, 2)
+	state(breakerOpen, 2)
+	if st := u.BreakerStates()[0]; st.OpenUntil == nil || !st.OpenUntil.Equal(clock.now.Add(cooldown)) {
+		t.Errorf("OpenUntil = %v, want %v", st.OpenUntil, clock.now.Add(cooldown))
+	}
+
+	// Open: calls are refused without reaching the host.
+	call(true, 2)
+
+	// After the cooldown one trial goes through; its failure re-opens.
+	clock.advance(cooldown)
+	state(breakerHalfOpen, 2)
+	call(false, 3)
+	state(breakerOpen, 3)
+	call(true, 3)
+
+	// A successful trial closes the breaker again.
+	clock.advance(cooldown)
+	call(false, 4)
+	state(breakerClosed, 0)
+	call(false, 5)
+}
+
+func TestUpstreamBreakerIgnoresClientFaults(t *testing.T) {
+	srv := newScriptedServer(t, 404, 404)
+	u := newTestUpstream(srv, UpstreamOptions{BreakerThreshold: 1}, &testClock{now: time.Now()})
+
+	for i := 0; i < 2; i++ {
+		if err := u.GetJSON(context.Background(), srv.URL, &okBody{}); errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("call %d refused after a 404", i)
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := u.GetJSON(ctx, srv.URL, &okBody{}); !errors.Is(err, context.Canceled) {
+		t.Errorf("GetJSON with a cancelled ctx = %v, want %v", err, context.Canceled)
+	}
+	if st := u.BreakerStates(); len(st) != 1 || st[0].State != breakerClosed {
+		t.Errorf("BreakerStates = %+v, want closed", st)
+	}
+}
+
+func TestUpstreamProbe(t *testing.T) {
+	srv := newScriptedServer(t, 500, 503)
+	clock := &testClock{now: time.Now()}
+	u := newTestUpstream(srv, UpstreamOptions{BreakerThreshold: 1}, clock)
+	ctx := context.Background()
+
+	// Open the breaker; a probe still reaches the host.
+	if err := u.GetJSON(ctx, srv.URL, &okBody{}); err == nil {
+		t.Fatal("GetJSON succeeded against a 500")
+	}
+	res := u.Probe(ctx, srv.URL)
+	if !res.Reachable || res.StatusCode != 503 || res.Error != "" {
+		t.Errorf("Probe = %+v, want reachable with 503", res)
+	}
+	if n := srv.hitCount(); n != 2 {
+		t.Errorf("upstream hit %d times, want 2", n)
+	}
+
+	srv.Close()
+	if res := u.Probe(ctx, srv.URL); res.Reachable || res.Error == "" {
+		t.Errorf("Probe of a closed server = %+v, want unreachable with an error", res)
+	}
+}
@@ -1,150 +1,242 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
-	"net/http"
-	"net/url"
+	"strings"
 	"time"
 
 	"github.com/jeffhieun/weatherdatadashboard/internal/model"
 	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type DefaultWeatherService struct {
 	repo     store.WeatherRepository
+	provider WeatherProvider
 	cacheTTL time.Duration
+	// staleTTL is how long past cacheTTL a value may still be served while it
+	// is refreshed in the background; zero disables stale-while-revalidate.
+	staleTTL  time.Duration
+	refresher *refreshPool
+	// flight merges concurrent cache misses for the same key into a single
+	// upstream fetch whose result is shared by all waiters.
+	flight flightGroup
 }
 
-func NewDefaultWeatherService(repo store.WeatherRepository, cacheTTL time.Duration) *DefaultWeatherService {
-	return &DefaultWeatherService{repo: repo, cacheTTL: cacheTTL}
+func NewDefaultWeatherService(repo store.WeatherRepository, provider WeatherProvider, cacheTTL time.Duration) *DefaultWeatherService {
+	return &DefaultWeatherService{repo: repo, provider: provider, cacheTTL: cacheTTL}
+}
+
+// EnableStaleWhileRevalidate keeps cached weather for staleTTL beyond the
+// cache TTL. In that window it is returned immediately, flagged as stale, and
+// refreshed in the background by at most workers concurrent fetches. Call it
+// before serving requests.
+func (s *DefaultWeatherService) EnableStaleWhileRevalidate(staleTTL time.Duration, workers int) {
+	s.staleTTL = staleTTL
+	s.refresher = newRefreshPool(workers, workers*16)
+}
+
+// Shutdown stops the background refresh workers, waiting for queued
+// refreshes until ctx ends and cancelling the rest after that.
+func (s *DefaultWeatherService) Shutdown(ctx context.Context) error {
+	if s.refresher == nil {
+		return nil
+	}
+	return s.refresher.close(ctx)
 }
 
 // GetWeatherDetails fetches and normalizes detailed weather data for a city.
-func (s *DefaultWeatherService) GetWeatherDetails(city string) (model.WeatherDetails, error) {
-	if data, ok := s.repo.Get(city); ok {
-		// Append a historical snapshot with refreshed timestamp to track views over time
-		snap := data
-		snap.UpdatedAt = time.Now()
-		s.repo.AppendHistory(snap.City, snap)
-		return data, nil
+func (s *DefaultWeatherService) GetWeatherDetails(ctx context.Context, city string) (_ model.WeatherDetails, err error) {
+	ctx, span := tracing.Start(ctx, "weather.details", tracing.AttrCity.String(city))
+	defer func() { tracing.End(span, err) }()
+	ctx = util.WithLogAttrs(ctx, "city", city)
+	return s.cachedOrFetch(ctx, store.CacheKey(city), "", func(ctx context.Context) (model.WeatherDetails, error) {
+		loc, err := s.provider.Geocode(ctx, city)
+		if err != nil {
+			return model.WeatherDetails{}, err
+		}
+		details, err := s.provider.Current(ctx, loc.Lat, loc.Lon)
+		if err != nil {
+			return model.WeatherDetails{}, err
+		}
+		details.City = loc.Name
+		return details, nil
+	})
+}
+
+// GetWeatherByCoords fetches weather for exact coordinates without geocoding,
+// so same-named cities cannot be confused. Results are cached under the
+// coordinates rounded to two decimals (~1 km) without a name, so callers
+// with different labels share them. label names the response and, when
+// given, the history entry; unlabelled lookups are not recorded in history
+// and are named by the rounded coordinates.
+func (s *DefaultWeatherService) GetWeatherByCoords(ctx context.Context, lat, lon float64, label string) (_ model.WeatherDetails, err error) {
+	key := coordKey(lat, lon)
+	name := label
+	if name == "" {
+		name = strings.TrimPrefix(key, "coord:")
 	}
-	geoURL := fmt.Sprintf("https://geocoding-api.open-meteo.com/v1/search?name=%s&count=1&language=en&format=json", url.QueryEscape(city))
-	resp, err := http.Get(geoURL)
+	ctx, span := tracing.Start(ctx, "weather.coords", tracing.AttrCity.String(name), attribute.Float64("lat", lat), attribute.Float64("lon", lon))
+	defer func() { tracing.End(span, err) }()
+	ctx = util.WithLogAttrs(ctx, "city", name)
+	data, err := s.cachedOrFetch(ctx, key, label, func(ctx context.Context) (model.WeatherDetails, error) {
+		return s.provider.Current(ctx, lat, lon)
+	})
 	if err != nil {
-		return model.WeatherDetails{}, fmt.Errorf("failed to geocode city: %w", err)
-	}
-	defer resp.Body.Close()
-	var geo struct {
-		Results []struct {
-			Name      string  `json:"name"`
-			Latitude  float64 `json:"latitude"`
-			Longitude float64 `json:"longitude"`
-			Country   string  `json:"country"`
-		} `json:"results"`
+		return model.WeatherDetails{}, err
 	}
-	if err := json.NewDecoder(resp.Body).Decode(&geo); err != nil {
-		return model.WeatherDetails{}, fmt.Errorf("invalid geocoding response: %w", err)
+	data.City = name
+	return data, nil
+}
+
+// cachedOrFetch serves key from the cache, recording a view in history, or
+// calls fetch and caches the result. label, if set, names the returned copy
+// and its history entries in place of the cached City; an entry with
+// neither is not recorded. Concurrent misses for the same key share one
+// fetch, so only one caller writes the cache and history entry. A stale
+// entry is returned as-is while a background refresh is queued.
+func (s *DefaultWeatherService) cachedOrFetch(ctx context.Context, key, label string, fetch fetchFunc) (model.WeatherDetails, error) {
+	start := time.Now()
+	rec, ok := s.repo.GetRecord(ctx, key)
+	tracing.Annotate(ctx, tracing.AttrCacheKey.String(key), tracing.AttrCacheHit.Bool(ok))
+	if ok {
+		data := withLabel(rec.Weather, label)
+		// Append a historical snapshot with refreshed timestamp to track views over time
+		if data.City != "" {
+			snap := data
+			snap.UpdatedAt = time.Now()
+			s.repo.AppendHistory(ctx, snap.City, snap)
+		}
+		if rec.Stale(time.Now()) && s.refresher != nil {
+			// The refresh outlives the request but keeps its log attributes.
+			bg := util.WithLogAttrs(context.WithoutCancel(ctx), "refresh", true)
+			s.refresher.submit(bg, key, func(ctx context.Context) {
+				_, _ = s.fetchAndStore(ctx, key, label, fetch)
+			})
+			data.Stale = true
+		}
+		util.Log(ctx).Info("cache hit", "key", key, "stale", data.Stale, "duration_ms", time.Since(start).Milliseconds())
+		return data, nil
 	}
-	if len(geo.Results) == 0 {
-		return model.WeatherDetails{}, fmt.Errorf("city not found")
+	data, err := s.fetchAndStore(ctx, key, label, fetch)
+	log := util.Log(ctx).With("key", key, "duration_ms", time.Since(start).Milliseconds())
+	if err != nil {
+		log.Warn("cache miss", "err", err)
+	} else {
+		log.Info("cache miss")
 	}
-	lat := geo.Results[0].Latitude
-	lon := geo.Results[0].Longitude
-	cityName := geo.Results[0].Name
+	return data, err
+}
+
+// fetchFunc loads fresh weather for a cache key.
+type fetchFunc func(ctx context.Context) (model.WeatherDetails, error)
 
-	weatherURL := fmt.Sprintf("https://api.open-meteo.com/v1/forecast?latitude=%.4f&longitude=%.4f&current_weather=true&hourly=temperature_2m,apparent_temperature,relative_humidity_2m,precipitation_probability,rain,snowfall,cloudcover,uv_index,visibility,surface_pressure,windspeed_10m,winddirection_10m&daily=sunrise,sunset&timezone=auto", lat, lon)
-	respW, err := http.Get(weatherURL)
+// fetchAndStore runs fetch once per key at a time and caches the result with
+// the configured soft and hard TTLs. The fetch is cancelled once every caller
+// waiting for it has gone. History is recorded under the label of the caller
+// that started the fetch.
+func (s *DefaultWeatherService) fetchAndStore(ctx context.Context, key, label string, fetch fetchFunc) (model.WeatherDetails, error) {
+	v, err := s.flight.Do(ctx, key, func(ctx context.Context) (interface{}, error) {
+		details, err := fetch(ctx)
+		if err != nil {
+			return nil, err
+		}
+		// Keep what was fetched even if the callers have just given up.
+		ctx = context.WithoutCancel(ctx)
+		now := time.Now()
+		s.repo.SetRecord(ctx, key, store.CacheRecord{
+			Weather:   details,
+			StaleAt:   now.Add(s.cacheTTL),
+			ExpiresAt: now.Add(s.cacheTTL + s.staleTTL),
+		})
+		// Also record in history under canonical city name
+		if snap := withLabel(details, label); snap.City != "" {
+			s.repo.AppendHistory(ctx, snap.City, snap)
+		}
+		return details, nil
+	})
 	if err != nil {
-		return model.WeatherDetails{}, fmt.Errorf("failed to fetch weather: %w", err)
-	}
-	defer respW.Body.Close()
-	var wres struct {
-		CurrentWeather struct {
-			Temperature float64 `json:"temperature"`
-			WindSpeed   float64 `json:"windspeed"`
-			WindDir     float64 `json:"winddirection"`
-			WeatherCode int     `json:"weathercode"`
-			Time        string  `json:"time"`
-		} `json:"current_weather"`
-		Hourly struct {
-			Time                []string  `json:"time"`
-			Temperature2m       []float64 `json:"temperature_2m"`
-			ApparentTemperature []float64 `json:"apparent_temperature"`
-			RelativeHumidity2m  []float64 `json:"relative_humidity_2m"`
-			PrecipitationProb   []float64 `json:"precipitation_probability"`
-			Rain                []float64 `json:"rain"`
-			Snowfall            []float64 `json:"snowfall"`
-			CloudCover          []float64 `json:"cloudcover"`
-			UVIndex             []float64 `json:"uv_index"`
-			Visibility          []float64 `json:"visibility"`
-			SurfacePressure     []float64 `json:"surface_pressure"`
-		} `json:"hourly"`
-		Daily struct {
-			Sunrise []string `json:"sunrise"`
-			Sunset  []string `json:"sunset"`
-		} `json:"daily"`
-	}
-	if err := json.NewDecoder(respW.Body).Decode(&wres); err != nil {
-		return model.WeatherDetails{}, fmt.Errorf("invalid weather response: %w", err)
+		return model.WeatherDetails{}, err
 	}
+	return withLabel(v.(model.WeatherDetails), label), nil
+}
 
-	// Find the index for the current hour
-	idx := 0
-	for i, t := range wres.Hourly.Time {
-		if t == wres.CurrentWeather.Time {
-			idx = i
-			break
-		}
+// withLabel returns data named label, or unchanged if label is empty.
+func withLabel(data model.WeatherDetails, label string) model.WeatherDetails {
+	if label != "" {
+		data.City = label
 	}
+	return data
+}
+
+func coordKey(lat, lon float64) string {
+	return fmt.Sprintf("coord:%.2f,%.2f", lat, lon)
+}
 
-	sunrise, _ := time.Parse(time.RFC3339, wres.Daily.Sunrise[0])
-	sunset, _ := time.Parse(time.RFC3339, wres.Daily.Sunset[0])
-
-	details := model.WeatherDetails{
-		City:        cityName,
-		Temperature: wres.CurrentWeather.Temperature,
-		FeelsLike:   wres.Hourly.ApparentTemperature[idx],
-		Humidity:    int(wres.Hourly.RelativeHumidity2m[idx]),
-		WindSpeed:   wres.CurrentWeather.WindSpeed,
-		WindDir:     fmt.Sprintf("%.0f°", wres.CurrentWeather.WindDir),
-		Visibility:  wres.Hourly.Visibility[idx] / 1000.0,
-		Pressure:    int(wres.Hourly.SurfacePressure[idx]),
-		UVIndex:     int(wres.Hourly.UVIndex[idx]),
-		Sunrise:     sunrise,
-		Sunset:      sunset,
-		CloudCover:  int(wres.Hourly.CloudCover[idx]),
-		PrecipProb:  wres.Hourly.PrecipitationProb[idx] / 100.0,
-		Rain:        wres.Hourly.Rain[idx],
-		Snow:        wres.Hourly.Snowfall[idx],
-		UpdatedAt:   time.Now(),
+// GetForecast returns the hourly and daily forecast for a city over the next
+// days, served from the repository cache when possible.
+func (s *DefaultWeatherService) GetForecast(ctx context.Context, city string, days int) (_ model.Forecast, err error) {
+	ctx, span := tracing.Start(ctx, "weather.forecast", tracing.AttrCity.String(city), attribute.Int("days", days))
+	defer func() { tracing.End(span, err) }()
+	ctx = util.WithLogAttrs(ctx, "city", city)
+	start := time.Now()
+	key := fmt.Sprintf("%s|%d", store.CacheKey(city), days)
+	fc, ok := s.repo.GetForecast(ctx, key)
+	span.SetAttributes(tracing.AttrCacheKey.String(key), tracing.AttrCacheHit.Bool(ok))
+	if ok {
+		util.Log(ctx).Info("forecast cache hit", "key", key, "duration_ms", time.Since(start).Milliseconds())
+		return fc, nil
 	}
-	s.repo.Set(city, details, s.cacheTTL)
-	// Also record in history under canonical city name
-	s.repo.AppendHistory(details.City, details)
-	return details, nil
+	v, err := s.flight.Do(ctx, "forecast:"+key, func(ctx context.Context) (interface{}, error) {
+		loc, err := s.provider.Geocode(ctx, city)
+		if err != nil {
+			return nil, err
+		}
+		fc, err := s.provider.Forecast(ctx, loc.Lat, loc.Lon, 0, days)
+		if err != nil {
+			return nil, err
+		}
+		fc.City = loc.Name
+		s.repo.SetForecast(context.WithoutCancel(ctx), key, fc, s.cacheTTL)
+		return fc, nil
+	})
+	log := util.Log(ctx).With("key", key, "duration_ms", time.Since(start).Milliseconds())
+	if err != nil {
+		log.Warn("forecast cache miss", "err", err)
+		return model.Forecast{}, err
+	}
+	log.Info("forecast cache miss")
+	return v.(model.Forecast), nil
 }
 
 // ...existing code...
 // GetCached returns a cached value for a city if present (and not expired).
-func (s *DefaultWeatherService) GetCached(city string) (model.WeatherDetails, bool) {
-	if data, ok := s.repo.Get(city); ok {
+func (s *DefaultWeatherService) GetCached(ctx context.Context, city string) (model.WeatherDetails, bool) {
+	if data, ok := s.repo.Get(ctx, store.CacheKey(city)); ok {
 		return data, true
 	}
 	return model.WeatherDetails{}, false
 }
 
 // ListCached returns all cached weather details as a map of city to WeatherDetails.
-func (s *DefaultWeatherService) ListCached() map[string]model.WeatherDetails {
-	return s.repo.List()
+func (s *DefaultWeatherService) ListCached(ctx context.Context) map[string]model.WeatherDetails {
+	return s.repo.List(ctx)
 }
 
 // ListHistory returns all historical snapshots for a specific city.
-func (s *DefaultWeatherService) ListHistory(city string) []model.WeatherDetails {
-	return s.repo.ListHistory(city)
+func (s *DefaultWeatherService) ListHistory(ctx context.Context, city string) []model.WeatherDetails {
+	return s.repo.ListHistory(ctx, city)
 }
 
 // ListAllHistory returns historical snapshots grouped by city.
-func (s *DefaultWeatherService) ListAllHistory() map[string][]model.WeatherDetails {
-	return s.repo.ListAllHistory()
+func (s *DefaultWeatherService) ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails {
+	return s.repo.ListAllHistory(ctx)
+}
+
+// QueryHistory returns historical snapshots matching q, grouped by city.
+func (s *DefaultWeatherService) QueryHistory(ctx context.Context, q store.HistoryQuery) map[string][]model.WeatherDetails {
+	return s.repo.QueryHistory(ctx, q)
 }
@@ -0,0 +1,103 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+)
+
+// fakeProvider counts upstream calls. When release is set, Current blocks
+// until it is closed or the fetch is cancelled.
+type fakeProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *fakeProvider) Geocode(ctx context.Context, city string) (model.City, error) {
+	return model.City{Name: "Hanoi", Country: "Vietnam", Lat: 21.03, Lon: 105.85}, nil
+}
+
+func (p *fakeProvider) Current(ctx context.Context, lat, lon float64) (model.WeatherDetails, error) {
+	p.calls.Add(1)
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+			return model.WeatherDetails{}, ctx.Err()
+		}
+	}
+	return model.WeatherDetails{Temperature: 30, UpdatedAt: time.Now()}, nil
+}
+
+func (p *fakeProvider) Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (model.Forecast, error) {
+	return model.Forecast{}, nil
+}
+
+func TestGetWeatherByCoordsLabel(t *testing.T) {
+	repo := store.NewInMemoryRepository()
+	svc := NewDefaultWeatherService(repo, &fakeProvider{}, time.Minute)
+	ctx := context.Background()
+
+	first, err := svc.GetWeatherByCoords(ctx, 21.0285, 105.8542, "Home")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := svc.GetWeatherByCoords(ctx, 21.0285, 105.8542, "Office")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, err := svc.GetWeatherByCoords(ctx, 21.0285, 105.8542, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.City != "Home" || second.City != "Office" || plain.City != "21.03,105.85" {
+		t.Errorf("names = %q, %q, %q; want Home, Office and the rounded coordinates", first.City, second.City, plain.City)
+	}
+	if cached, _ := repo.Get(ctx, coordKey(21.0285, 105.8542)); cached.City != "" {
+		t.Errorf("cached entry is named %q, want no name", cached.City)
+	}
+	history := repo.ListAllHistory(ctx)
+	if len(history) != 2 || len(history["Home"]) != 1 || len(history["Office"]) != 1 {
+		t.Errorf("history = %v, want one entry each under Home and Office", history)
+	}
+}
+
+func TestConcurrentMissesShareOneFetch(t *testing.T) {
+	provider := &fakeProvider{release: make(chan struct{})}
+	svc := NewDefaultWeatherService(store.NewInMemoryRepository(), provider, time.Minute)
+
+	const callers = 50
+	var wg sync.WaitGroup
+	errs := make(chan error, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			data, err := svc.GetWeatherDetails(context.Background(), "Hanoi")
+			if err == nil && data.Temperature != 30 {
+				err = fmt.Errorf("temperature %v, want 30", data.Temperature)
+			}
+			errs <- err
+		}()
+	}
+	// Hold the first fetch until every caller has joined it.
+	waitForWaiters(t, &svc.flight, store.CacheKey("Hanoi"), callers)
+	close(provider.release)
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			t.Error(err)
+		}
+	}
+	if n := provider.calls.Load(); n != 1 {
+		t.Errorf("upstream called %d times, want 1", n)
+	}
+}
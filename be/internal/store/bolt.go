@@ -0,0 +1,581 @@
+package store
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	bolt "go.etcd.io/bbolt"
+)
+
+var (
+	boltCacheBucket    = []byte("cache")
+	boltForecastBucket = []byte("forecast")
+	boltHistoryBucket  = []byte("history")
+	boltFloodBucket    = []byte("flood")
+)
+
+// BoltRepository is a file-backed WeatherRepository. Cache entries live in a
+// single bucket and are deleted by the first read that finds them expired;
+// history is stored in one nested bucket per city whose keys
+// are the big-endian UpdatedAt timestamp followed by a sequence number, so
+// the (city, UpdatedAt) index is simply the key order. Flood results are
+// kept the same way, in one nested bucket per city keyed by AssessedAt.
+type BoltRepository struct {
+	db *bolt.DB
+}
+
+// NewBoltRepository opens (or creates) the database file at path.
+func NewBoltRepository(path string) (*BoltRepository, error) {
+	db, err := bolt.Open(path, 0o600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		for _, name := range [][]byte{boltCacheBucket, boltForecastBucket, boltHistoryBucket, boltFloodBucket} {
+			if _, err := tx.CreateBucketIfNotExists(name); err != nil {
+				return err
+			}
+		}
+		return migrateBoltFloodResults(tx.Bucket(boltFloodBucket))
+	})
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialise store %s: %w", path, err)
+	}
+	return &BoltRepository{db: db}, nil
+}
+
+func (r *BoltRepository) Get(ctx context.Context, city string) (model.WeatherDetails, bool) {
+	rec, ok := r.GetRecord(ctx, city)
+	return rec.Weather, ok
+}
+
+func (r *BoltRepository) Set(ctx context.Context, city string, data model.WeatherDetails, ttl time.Duration) {
+	r.SetRecord(ctx, city, CacheRecord{Weather: data, ExpiresAt: time.Now().Add(ttl)})
+}
+
+func (r *BoltRepository) GetRecord(ctx context.Context, city string) (CacheRecord, bool) {
+	var rec CacheRecord
+	found := false
+	err := r.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltCacheBucket).Get([]byte(city))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, &rec)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store get", "city", city, "err", err)
+		return CacheRecord{}, false
+	}
+	if !found {
+		return CacheRecord{}, false
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		r.deleteExpired(ctx, boltCacheBucket, city)
+		return CacheRecord{}, false
+	}
+	return rec, true
+}
+
+func (r *BoltRepository) SetRecord(ctx context.Context, city string, rec CacheRecord) {
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		util.Log(ctx).Error("store encode", "city", city, "err", err)
+		return
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCacheBucket).Put([]byte(city), raw)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store set", "city", city, "err", err)
+	}
+}
+
+func (r *BoltRepository) List(ctx context.Context) map[string]model.WeatherDetails {
+	result := make(map[string]model.WeatherDetails)
+	var expired []string
+	now := time.Now()
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltCacheBucket).ForEach(func(k, v []byte) error {
+			var rec CacheRecord
+			if err := json.Unmarshal(v, &rec); err != nil {
+				return nil
+			}
+			if now.Before(rec.ExpiresAt) {
+				result[string(k)] = rec.Weather
+			} else {
+				expired = append(expired, string(k))
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		util.Log(ctx).Error("store list", "err", err)
+	}
+	if len(expired) > 0 {
+		r.deleteExpired(ctx, boltCacheBucket, expired...)
+	}
+	return result
+}
+
+func (r *BoltRepository) GetForecast(ctx context.Context, key string) (model.Forecast, bool) {
+	var rec ForecastRecord
+	found := false
+	err := r.db.View(func(tx *bolt.Tx) error {
+		raw := tx.Bucket(boltForecastBucket).Get([]byte(key))
+		if raw == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(raw, &rec)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store get forecast", "key", key, "err", err)
+		return model.Forecast{}, false
+	}
+	if !found {
+		return model.Forecast{}, false
+	}
+	if time.Now().After(rec.ExpiresAt) {
+		r.deleteExpired(ctx, boltForecastBucket, key)
+		return model.Forecast{}, false
+	}
+	return rec.Forecast, true
+}
+
+func (r *BoltRepository) SetForecast(ctx context.Context, key string, data model.Forecast, ttl time.Duration) {
+	raw, err := json.Marshal(ForecastRecord{Forecast: data, ExpiresAt: time.Now().Add(ttl)})
+	if err != nil {
+		util.Log(ctx).Error("store encode forecast", "key", key, "err", err)
+		return
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltForecastBucket).Put([]byte(key), raw)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store set forecast", "key", key, "err", err)
+	}
+}
+
+// deleteExpired removes the given cache or forecast entries if they are
+// still expired; one rewritten since it was read is kept.
+func (r *BoltRepository) deleteExpired(ctx context.Context, bucket []byte, keys ...string) {
+	now := time.Now()
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		b := tx.Bucket(bucket)
+		for _, key := range keys {
+			var rec struct{ ExpiresAt time.Time }
+			raw := b.Get([]byte(key))
+			if raw == nil || json.Unmarshal(raw, &rec) != nil || now.Before(rec.ExpiresAt) {
+				continue
+			}
+			if err := b.Delete([]byte(key)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		util.Log(ctx).Error("store delete expired", "bucket", string(bucket), "err", err)
+	}
+}
+
+// Ping opens a read transaction, which fails once the database is closed.
+func (r *BoltRepository) Ping(ctx context.Context) error {
+	return r.db.View(func(tx *bolt.Tx) error { return nil })
+}
+
+func (r *BoltRepository) Close() {
+	if err := r.db.Close(); err != nil {
+		util.Logger.Error("store close", "err", err)
+	}
+}
+
+// AppendHistory durably records a snapshot under the city's history bucket.
+func (r *BoltRepository) AppendHistory(ctx context.Context, city string, data model.WeatherDetails) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		util.Log(ctx).Error("store encode", "city", city, "err", err)
+		return
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(boltHistoryBucket).CreateBucketIfNotExists([]byte(city))
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(boltHistoryKey(data.UpdatedAt, seq), raw)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store append history", "city", city, "err", err)
+	}
+}
+
+// ListHistory returns all historical records for a city, oldest first.
+func (r *BoltRepository) ListHistory(ctx context.Context, city string) []model.WeatherDetails {
+	out := []model.WeatherDetails{}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltHistoryBucket).Bucket([]byte(city))
+		if b == nil {
+			return nil
+		}
+		out = scanBoltHistory(b, HistoryQuery{})
+		return nil
+	})
+	if err != nil {
+		util.Log(ctx).Error("store list history", "city", city, "err", err)
+	}
+	return out
+}
+
+// ListAllHistory returns historical records grouped by city.
+func (r *BoltRepository) ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails {
+	return r.QueryHistory(ctx, HistoryQuery{})
+}
+
+// QueryHistory returns historical records matching q, grouped by city. Only
+// the key range inside [q.From, q.To) is visited for each matching city.
+func (r *BoltRepository) QueryHistory(ctx context.Context, q HistoryQuery) map[string][]model.WeatherDetails {
+	out := make(map[string][]model.WeatherDetails)
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltHistoryBucket).ForEachBucket(func(name []byte) error {
+			city := string(name)
+			if !q.matchesCity(city) {
+				return nil
+			}
+			b := tx.Bucket(boltHistoryBucket).Bucket(name)
+			if list := scanBoltHistory(b, q); len(list) > 0 {
+				out[city] = list
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		util.Log(ctx).Error("store query history", "err", err)
+	}
+	return out
+}
+
+// HistoryCities lists the cities with a history bucket.
+func (r *BoltRepository) HistoryCities(ctx context.Context) []string {
+	var out []string
+	err := r.db.View(func(tx *bolt.Tx) error {
+		return tx.Bucket(boltHistoryBucket).ForEachBucket(func(name []byte) error {
+			out = append(out, string(name))
+			return nil
+		})
+	})
+	if err != nil {
+		util.Log(ctx).Error("store history cities", "err", err)
+	}
+	return out
+}
+
+// CompactHistory rewrites the city's history bucket in one transaction, so
+// appends wait for it instead of being lost.
+func (r *BoltRepository) CompactHistory(ctx context.Context, city string, compact CompactFunc) error {
+	return r.db.Update(func(tx *bolt.Tx) error {
+		history := tx.Bucket(boltHistoryBucket)
+		b := history.Bucket([]byte(city))
+		if b == nil {
+			return nil
+		}
+		out, changed := compact(scanBoltHistory(b, HistoryQuery{}))
+		if !changed {
+			return nil
+		}
+		if err := history.DeleteBucket([]byte(city)); err != nil {
+			return err
+		}
+		if len(out) == 0 {
+			return nil
+		}
+		b, err := history.CreateBucket([]byte(city))
+		if err != nil {
+			return err
+		}
+		for _, data := range out {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			seq, err := b.NextSequence()
+			if err != nil {
+				return err
+			}
+			if err := b.Put(boltHistoryKey(data.UpdatedAt, seq), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltFloodCity names the nested bucket holding a city's flood results.
+// Bucket names cannot be empty, so results without a city get a name no
+// city produces.
+func boltFloodCity(city string) []byte {
+	if city == "" {
+		return []byte{0}
+	}
+	return []byte(floodKey(city))
+}
+
+// migrateBoltFloodResults moves flood results written before they were
+// split per city, which sit directly in the flood bucket, into their city's
+// bucket.
+func migrateBoltFloodResults(flood *bolt.Bucket) error {
+	var keys, values [][]byte
+	err := flood.ForEach(func(k, v []byte) error {
+		if v != nil {
+			keys, values = append(keys, k), append(values, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for i, k := range keys {
+		var data model.FloodAssessment
+		if err := json.Unmarshal(values[i], &data); err == nil {
+			b, err := flood.CreateBucketIfNotExists(boltFloodCity(data.City))
+			if err != nil {
+				return err
+			}
+			if err := b.Put(k, values[i]); err != nil {
+				return err
+			}
+		}
+		if err := flood.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AppendFloodResult durably records a flood assessment under the city's
+// bucket, keyed by AssessedAt.
+func (r *BoltRepository) AppendFloodResult(ctx context.Context, data model.FloodAssessment) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		util.Log(ctx).Error("store encode flood result", "err", err)
+		return
+	}
+	err = r.db.Update(func(tx *bolt.Tx) error {
+		b, err := tx.Bucket(boltFloodBucket).CreateBucketIfNotExists(boltFloodCity(data.City))
+		if err != nil {
+			return err
+		}
+		seq, err := b.NextSequence()
+		if err != nil {
+			return err
+		}
+		return b.Put(boltHistoryKey(data.AssessedAt, seq), raw)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store append flood result", "city", data.City, "err", err)
+	}
+}
+
+// QueryFloodResults returns flood assessments matching q, oldest first. Only
+// the key range inside [q.From, q.To) of the matching cities is visited.
+func (r *BoltRepository) QueryFloodResults(ctx context.Context, q HistoryQuery) []model.FloodAssessment {
+	out := make([]model.FloodAssessment, 0)
+	scan := func(b *bolt.Bucket) {
+		scanBoltRange(b, q, func(v []byte) {
+			var data model.FloodAssessment
+			if err := json.Unmarshal(v, &data); err != nil {
+				return
+			}
+			if q.matchesCity(data.City) {
+				out = append(out, data)
+			}
+		})
+	}
+	err := r.db.View(func(tx *bolt.Tx) error {
+		flood := tx.Bucket(boltFloodBucket)
+		if q.City != "" {
+			if b := flood.Bucket(boltFloodCity(q.City)); b != nil {
+				scan(b)
+			}
+			return nil
+		}
+		err := flood.ForEachBucket(func(name []byte) error {
+			scan(flood.Bucket(name))
+			return nil
+		})
+		sortFloodResults(out)
+		return err
+	})
+	if err != nil {
+		util.Log(ctx).Error("store query flood results", "err", err)
+	}
+	return out
+}
+
+// LatestFloodResult reads the last key of the city's bucket.
+func (r *BoltRepository) LatestFloodResult(ctx context.Context, city string) (model.FloodAssessment, bool) {
+	var data model.FloodAssessment
+	found := false
+	err := r.db.View(func(tx *bolt.Tx) error {
+		b := tx.Bucket(boltFloodBucket).Bucket(boltFloodCity(city))
+		if b == nil {
+			return nil
+		}
+		_, v := b.Cursor().Last()
+		if v == nil {
+			return nil
+		}
+		found = true
+		return json.Unmarshal(v, &data)
+	})
+	if err != nil {
+		util.Log(ctx).Error("store latest flood result", "city", city, "err", err)
+		return model.FloodAssessment{}, false
+	}
+	return data, found
+}
+
+// TrimFloodResults deletes the oldest keys of every city's bucket in one
+// transaction, and the buckets left empty.
+func (r *BoltRepository) TrimFloodResults(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	removed := 0
+	err := r.db.Update(func(tx *bolt.Tx) error {
+		flood := tx.Bucket(boltFloodBucket)
+		var names [][]byte
+		err := flood.ForEachBucket(func(name []byte) error {
+			names = append(names, append([]byte(nil), name...))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		var end []byte
+		if !cutoff.IsZero() {
+			end = boltHistoryKey(cutoff, 0)
+		}
+		for _, name := range names {
+			b := flood.Bucket(name)
+			n := b.Stats().KeyN
+			var drop [][]byte
+			c := b.Cursor()
+			for k, _ := c.First(); k != nil; k, _ = c.Next() {
+				tooOld := end != nil && bytes.Compare(k, end) < 0
+				overLimit := keep > 0 && n-len(drop) > keep
+				if !tooOld && !overLimit {
+					break
+				}
+				drop = append(drop, append([]byte(nil), k...))
+			}
+			removed += len(drop)
+			if len(drop) == n {
+				if err := flood.DeleteBucket(name); err != nil {
+					return err
+				}
+				continue
+			}
+			for _, k := range drop {
+				if err := b.Delete(k); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return removed, nil
+}
+
+// Stats counts unexpired cache entries, history and flood results. History
+// sizes come from bucket key counts without decoding any snapshot.
+func (r *BoltRepository) Stats(ctx context.Context) Stats {
+	var st Stats
+	now := time.Now()
+	err := r.db.View(func(tx *bolt.Tx) error {
+		err := tx.Bucket(boltCacheBucket).ForEach(func(_, v []byte) error {
+			var rec struct{ ExpiresAt time.Time }
+			if json.Unmarshal(v, &rec) == nil && now.Before(rec.ExpiresAt) {
+				st.CachedEntries++
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		history := tx.Bucket(boltHistoryBucket)
+		err = history.ForEachBucket(func(name []byte) error {
+			st.HistoryCities++
+			st.HistorySnapshots += history.Bucket(name).Stats().KeyN
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		flood := tx.Bucket(boltFloodBucket)
+		return flood.ForEachBucket(func(name []byte) error {
+			st.FloodResults += flood.Bucket(name).Stats().KeyN
+			return nil
+		})
+	})
+	if err != nil {
+		util.Log(ctx).Error("store stats", "err", err)
+	}
+	return st
+}
+
+func scanBoltHistory(b *bolt.Bucket, q HistoryQuery) []model.WeatherDetails {
+	var out []model.WeatherDetails
+	scanBoltRange(b, q, func(v []byte) {
+		var data model.WeatherDetails
+		if err := json.Unmarshal(v, &data); err != nil {
+			return
+		}
+		out = append(out, data)
+	})
+	return out
+}
+
+// scanBoltRange calls fn for every value of a time-keyed bucket whose key
+// falls inside [q.From, q.To).
+func scanBoltRange(b *bolt.Bucket, q HistoryQuery, fn func(v []byte)) {
+	c := b.Cursor()
+	k, v := c.First()
+	if !q.From.IsZero() {
+		k, v = c.Seek(boltHistoryKey(q.From, 0))
+	}
+	var end []byte
+	if !q.To.IsZero() {
+		end = boltHistoryKey(q.To, 0)
+	}
+	for ; k != nil; k, v = c.Next() {
+		if end != nil && bytes.Compare(k, end) >= 0 {
+			break
+		}
+		fn(v)
+	}
+}
+
+// boltHistoryKey orders snapshots by time; seq disambiguates equal timestamps.
+func boltHistoryKey(t time.Time, seq uint64) []byte {
+	ns := t.UnixNano()
+	if ns < 0 {
+		ns = 0
+	}
+	key := make([]byte, 16)
+	binary.BigEndian.PutUint64(key[:8], uint64(ns))
+	binary.BigEndian.PutUint64(key[8:], seq)
+	return key
+}
@@ -0,0 +1,207 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	bolt "go.etcd.io/bbolt"
+)
+
+func newTestBolt(t *testing.T, path string) *BoltRepository {
+	t.Helper()
+	repo, err := NewBoltRepository(path)
+	if err != nil {
+		t.Fatalf("NewBoltRepository: %v", err)
+	}
+	return repo
+}
+
+func TestBoltReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weatherd.db")
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	repo := newTestBolt(t, path)
+	repo.Set(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", Temperature: 31}, time.Hour)
+	repo.Set(ctx, "oslo", model.WeatherDetails{City: "Oslo"}, -time.Second)
+	repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", UpdatedAt: base})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.4, AssessedAt: base})
+	repo.Close()
+
+	repo = newTestBolt(t, path)
+	defer repo.Close()
+	if got, ok := repo.Get(ctx, "hanoi"); !ok || got.Temperature != 31 {
+		t.Errorf("Get(hanoi) after reopen = %+v, %v; want cached entry", got, ok)
+	}
+	if _, ok := repo.Get(ctx, "oslo"); ok {
+		t.Error("Get(oslo) returned an expired entry")
+	}
+	if got := repo.ListHistory(ctx, "hanoi"); len(got) != 1 || !got[0].UpdatedAt.Equal(base) {
+		t.Errorf("ListHistory after reopen = %+v, want one snapshot", got)
+	}
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{}); len(got) != 1 || got[0].Score != 0.4 {
+		t.Errorf("QueryFloodResults after reopen = %+v, want one result", got)
+	}
+	if st := repo.Stats(ctx); st.CachedEntries != 1 || st.HistorySnapshots != 1 || st.FloodResults != 1 {
+		t.Errorf("Stats after reopen = %+v", st)
+	}
+}
+
+func TestBoltDeletesExpiredOnRead(t *testing.T) {
+	repo := newTestBolt(t, filepath.Join(t.TempDir(), "weatherd.db"))
+	defer repo.Close()
+	ctx := context.Background()
+
+	repo.Set(ctx, "hanoi", model.WeatherDetails{City: "Hanoi"}, time.Hour)
+	repo.Set(ctx, "oslo", model.WeatherDetails{City: "Oslo"}, -time.Second)
+	repo.Set(ctx, "paris", model.WeatherDetails{City: "Paris"}, -time.Second)
+	repo.SetForecast(ctx, "hanoi", model.Forecast{}, time.Hour)
+	repo.SetForecast(ctx, "oslo", model.Forecast{}, -time.Second)
+
+	keys := func(bucket []byte) int {
+		var n int
+		if err := repo.db.View(func(tx *bolt.Tx) error {
+			n = tx.Bucket(bucket).Stats().KeyN
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	if _, ok := repo.Get(ctx, "oslo"); ok {
+		t.Error("Get(oslo) returned an expired entry")
+	}
+	if n := keys(boltCacheBucket); n != 2 {
+		t.Errorf("cache bucket has %d keys after Get, want 2", n)
+	}
+	if list := repo.List(ctx); len(list) != 1 {
+		t.Errorf("List = %v, want only hanoi", list)
+	}
+	if n := keys(boltCacheBucket); n != 1 {
+		t.Errorf("cache bucket has %d keys after List, want 1", n)
+	}
+	if _, ok := repo.GetForecast(ctx, "oslo"); ok {
+		t.Error("GetForecast(oslo) returned an expired entry")
+	}
+	if n := keys(boltForecastBucket); n != 1 {
+		t.Errorf("forecast bucket has %d keys, want 1", n)
+	}
+}
+
+func TestBoltFloodResults(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "weatherd.db")
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	// Write two results the way they were stored before the per-city
+	// buckets; reopening moves them.
+	repo := newTestBolt(t, path)
+	err := repo.db.Update(func(tx *bolt.Tx) error {
+		for i, data := range []model.FloodAssessment{
+			{City: "Hanoi", Score: 0.1, AssessedAt: base},
+			{City: "Oslo", Score: 0.3, AssessedAt: base.Add(30 * time.Minute)},
+		} {
+			raw, _ := json.Marshal(data)
+			if err := tx.Bucket(boltFloodBucket).Put(boltHistoryKey(data.AssessedAt, uint64(i)), raw); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo.Close()
+
+	repo = newTestBolt(t, path)
+	defer repo.Close()
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.4, AssessedAt: base.Add(2 * time.Hour)})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.2, AssessedAt: base.Add(time.Hour)})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{Score: 0.5, AssessedAt: base.Add(time.Hour)})
+
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{}); len(got) != 5 || got[1].City != "Oslo" || got[4].Score != 0.4 {
+		t.Errorf("QueryFloodResults = %+v, want all five oldest first", got)
+	}
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{City: "HANOI", From: base, To: base.Add(2 * time.Hour)}); len(got) != 2 || got[0].Score != 0.1 || got[1].Score != 0.2 {
+		t.Errorf("QueryFloodResults(HANOI) = %+v, want 0.1 then 0.2", got)
+	}
+	if got, ok := repo.LatestFloodResult(ctx, "hanoi"); !ok || got.Score != 0.4 {
+		t.Errorf("LatestFloodResult(hanoi) = %+v, %v; want 0.4", got, ok)
+	}
+	if got, ok := repo.LatestFloodResult(ctx, ""); !ok || got.Score != 0.5 {
+		t.Errorf("LatestFloodResult without a city = %+v, %v; want 0.5", got, ok)
+	}
+
+	n, err := repo.TrimFloodResults(ctx, base.Add(time.Hour), 1)
+	if err != nil || n != 3 {
+		t.Fatalf("TrimFloodResults = %d, %v; want 3 removed", n, err)
+	}
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{}); len(got) != 2 || got[0].Score != 0.5 || got[1].Score != 0.4 {
+		t.Errorf("results after trimming = %+v, want 0.5 and 0.4", got)
+	}
+	if _, ok := repo.LatestFloodResult(ctx, "oslo"); ok {
+		t.Error("LatestFloodResult(oslo) found a trimmed result")
+	}
+	if st := repo.Stats(ctx); st.FloodResults != 2 {
+		t.Errorf("Stats.FloodResults = %d, want 2", st.FloodResults)
+	}
+}
+
+func TestBoltQueryHistory(t *testing.T) {
+	repo := newTestBolt(t, filepath.Join(t.TempDir(), "weatherd.db"))
+	defer repo.Close()
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	to := from.Add(time.Hour)
+
+	snap := func(name string, at time.Time) {
+		repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", WindDir: name, UpdatedAt: at})
+	}
+	snap("to", to)
+	snap("before-from", from.Add(-time.Nanosecond))
+	snap("from-1", from)
+	snap("from-2", from)
+	snap("before-to", to.Add(-time.Nanosecond))
+	repo.AppendHistory(ctx, "oslo", model.WeatherDetails{City: "Oslo", UpdatedAt: from})
+
+	tests := []struct {
+		name string
+		q    HistoryQuery
+		want []string
+	}{
+		{"window", HistoryQuery{City: "hanoi", From: from, To: to}, []string{"from-1", "from-2", "before-to"}},
+		{"case-insensitive city", HistoryQuery{City: "HaNoI", From: from, To: to}, []string{"from-1", "from-2", "before-to"}},
+		{"open start", HistoryQuery{City: "hanoi", To: from}, []string{"before-from"}},
+		{"open end", HistoryQuery{City: "hanoi", From: to}, []string{"to"}},
+		{"unbounded", HistoryQuery{City: "hanoi"}, []string{"before-from", "from-1", "from-2", "before-to", "to"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := repo.QueryHistory(ctx, tt.q)
+			if _, ok := got["oslo"]; ok {
+				t.Errorf("result includes oslo")
+			}
+			list := got["hanoi"]
+			if len(list) != len(tt.want) {
+				t.Fatalf("got %d snapshots, want %v", len(list), tt.want)
+			}
+			for i, name := range tt.want {
+				if list[i].WindDir != name {
+					t.Errorf("snapshot %d = %s, want %s", i, list[i].WindDir, name)
+				}
+			}
+		})
+	}
+
+	if got := repo.QueryHistory(ctx, HistoryQuery{From: from, To: to}); len(got["oslo"]) != 1 || len(got["hanoi"]) != 3 {
+		t.Errorf("QueryHistory without a city = %v, want both cities", got)
+	}
+	if st := repo.Stats(ctx); st.HistorySnapshots != 6 {
+		t.Errorf("Stats.HistorySnapshots = %d, want 6 with equal timestamps kept apart", st.HistorySnapshots)
+	}
+}
@@ -0,0 +1,660 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/util"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	redisKeyPrefix      = "weatherd:"
+	redisCachePrefix    = redisKeyPrefix + "cache:"
+	redisCacheIndex     = redisKeyPrefix + "cache-index"
+	redisForecastPrefix = redisKeyPrefix + "forecast:"
+	redisHistoryPrefix  = redisKeyPrefix + "history:"
+	redisHistoryCities  = redisKeyPrefix + "history-cities"
+	redisFloodPrefix    = redisKeyPrefix + "flood:"
+	redisFloodCities    = redisKeyPrefix + "flood-cities"
+	// redisLegacyFloodResults is the single sorted set that held every
+	// city's flood results before they were split per city.
+	redisLegacyFloodResults = redisKeyPrefix + "flood-results"
+	redisOpTimeout          = 3 * time.Second
+	// redisCompactAttempts bounds the retries of a history rewrite or flood
+	// trim that keeps colliding with appends.
+	redisCompactAttempts = 5
+)
+
+// RedisRepository is a WeatherRepository backed by Redis so that several
+// weatherd replicas can share one cache and history survives restarts.
+// Cache entries rely on native key TTLs and are indexed in a sorted set
+// scored by expiry, so listing and counting them never scans the keyspace;
+// history is kept in one sorted set per city scored by UpdatedAt.
+type RedisRepository struct {
+	client redis.UniversalClient
+}
+
+// NewRedisRepository connects to the Redis server described by redisURL
+// (e.g. redis://localhost:6379/0) and verifies it is reachable.
+func NewRedisRepository(redisURL string) (*RedisRepository, error) {
+	opts, err := redis.ParseURL(redisURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis url: %w", err)
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), redisOpTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis: %w", err)
+	}
+	r := NewRedisRepositoryWithClient(client)
+	if err := r.migrateFloodResults(context.Background()); err != nil {
+		util.Logger.Warn("redis flood result migration", "err", err)
+	}
+	return r, nil
+}
+
+// redisMigrateBatch is how many legacy flood results are moved per round
+// trip.
+const redisMigrateBatch = 500
+
+// migrateFloodResults moves flood results from the legacy global sorted set
+// into the per-city ones, oldest first, in batches. The legacy set is gone
+// once it is empty. Replicas migrating at the same time only move a result
+// twice, which the sorted sets absorb.
+func (r *RedisRepository) migrateFloodResults(ctx context.Context) error {
+	for {
+		moved, err := r.migrateFloodBatch(ctx)
+		if err != nil || moved == 0 {
+			return err
+		}
+	}
+}
+
+func (r *RedisRepository) migrateFloodBatch(ctx context.Context) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	members, err := r.client.ZRange(ctx, redisLegacyFloodResults, 0, redisMigrateBatch-1).Result()
+	if err != nil || len(members) == 0 {
+		return 0, err
+	}
+	pipe := r.client.TxPipeline()
+	for _, m := range members {
+		var data model.FloodAssessment
+		if err := json.Unmarshal([]byte(m), &data); err == nil {
+			key := floodKey(data.City)
+			pipe.ZAdd(ctx, redisFloodPrefix+key, redis.Z{Score: float64(data.AssessedAt.UnixNano()), Member: m})
+			pipe.SAdd(ctx, redisFloodCities, key)
+		}
+		pipe.ZRem(ctx, redisLegacyFloodResults, m)
+	}
+	_, err = pipe.Exec(ctx)
+	return len(members), err
+}
+
+// NewRedisRepositoryWithClient wraps an existing client, e.g. one pointed at
+// an in-process Redis stand-in.
+func NewRedisRepositoryWithClient(client redis.UniversalClient) *RedisRepository {
+	return &RedisRepository{client: client}
+}
+
+func (r *RedisRepository) Get(ctx context.Context, city string) (model.WeatherDetails, bool) {
+	rec, ok := r.GetRecord(ctx, city)
+	return rec.Weather, ok
+}
+
+func (r *RedisRepository) Set(ctx context.Context, city string, data model.WeatherDetails, ttl time.Duration) {
+	r.SetRecord(ctx, city, CacheRecord{Weather: data, ExpiresAt: time.Now().Add(ttl)})
+}
+
+func (r *RedisRepository) GetRecord(ctx context.Context, city string) (CacheRecord, bool) {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	raw, err := r.client.Get(ctx, redisCachePrefix+city).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			util.Log(ctx).Error("redis get", "city", city, "err", err)
+		}
+		return CacheRecord{}, false
+	}
+	var rec CacheRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		util.Log(ctx).Error("redis decode", "city", city, "err", err)
+		return CacheRecord{}, false
+	}
+	return rec, true
+}
+
+// SetRecord stores rec with a native key TTL matching its hard expiry and
+// indexes it by that expiry. Index members that have already expired are
+// dropped on the way.
+func (r *RedisRepository) SetRecord(ctx context.Context, city string, rec CacheRecord) {
+	ttl := time.Until(rec.ExpiresAt)
+	if ttl <= 0 {
+		return
+	}
+	raw, err := json.Marshal(rec)
+	if err != nil {
+		util.Log(ctx).Error("redis encode", "city", city, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	pipe := r.client.TxPipeline()
+	pipe.Set(ctx, redisCachePrefix+city, raw, ttl)
+	pipe.ZAdd(ctx, redisCacheIndex, redis.Z{Score: float64(rec.ExpiresAt.UnixMilli()), Member: city})
+	pipe.ZRemRangeByScore(ctx, redisCacheIndex, "-inf", strconv.FormatInt(time.Now().UnixMilli(), 10))
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis set", "city", city, "err", err)
+	}
+}
+
+// liveCacheRange selects the cache index members that have not expired yet.
+func liveCacheRange() *redis.ZRangeBy {
+	return &redis.ZRangeBy{Min: "(" + strconv.FormatInt(time.Now().UnixMilli(), 10), Max: "+inf"}
+}
+
+func (r *RedisRepository) List(ctx context.Context) map[string]model.WeatherDetails {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	result := make(map[string]model.WeatherDetails)
+	cities, err := r.client.ZRangeByScore(ctx, redisCacheIndex, liveCacheRange()).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list cache index", "err", err)
+		return result
+	}
+	if len(cities) == 0 {
+		return result
+	}
+	keys := make([]string, len(cities))
+	for i, city := range cities {
+		keys[i] = redisCachePrefix + city
+	}
+	// Keys may expire or be deleted behind the index's back; those come back
+	// as nil.
+	values, err := r.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis mget", "err", err)
+		return result
+	}
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok {
+			continue
+		}
+		var rec CacheRecord
+		if err := json.Unmarshal([]byte(s), &rec); err != nil {
+			continue
+		}
+		result[cities[i]] = rec.Weather
+	}
+	return result
+}
+
+func (r *RedisRepository) GetForecast(ctx context.Context, key string) (model.Forecast, bool) {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	raw, err := r.client.Get(ctx, redisForecastPrefix+key).Bytes()
+	if err != nil {
+		if err != redis.Nil {
+			util.Log(ctx).Error("redis get forecast", "key", key, "err", err)
+		}
+		return model.Forecast{}, false
+	}
+	var data model.Forecast
+	if err := json.Unmarshal(raw, &data); err != nil {
+		util.Log(ctx).Error("redis decode forecast", "key", key, "err", err)
+		return model.Forecast{}, false
+	}
+	return data, true
+}
+
+func (r *RedisRepository) SetForecast(ctx context.Context, key string, data model.Forecast, ttl time.Duration) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		util.Log(ctx).Error("redis encode forecast", "key", key, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	if err := r.client.Set(ctx, redisForecastPrefix+key, raw, ttl).Err(); err != nil {
+		util.Log(ctx).Error("redis set forecast", "key", key, "err", err)
+	}
+}
+
+func (r *RedisRepository) Ping(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	return r.client.Ping(ctx).Err()
+}
+
+func (r *RedisRepository) Close() {
+	if err := r.client.Close(); err != nil {
+		util.Logger.Error("redis close", "err", err)
+	}
+}
+
+// AppendHistory adds a snapshot to the city's sorted set, scored by UpdatedAt.
+func (r *RedisRepository) AppendHistory(ctx context.Context, city string, data model.WeatherDetails) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		util.Log(ctx).Error("redis encode", "city", city, "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, redisHistoryPrefix+city, redis.Z{Score: float64(data.UpdatedAt.UnixNano()), Member: raw})
+	pipe.SAdd(ctx, redisHistoryCities, city)
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis append history", "city", city, "err", err)
+	}
+}
+
+// ListHistory returns all historical records for a city, oldest first.
+func (r *RedisRepository) ListHistory(ctx context.Context, city string) []model.WeatherDetails {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	members, err := r.client.ZRange(ctx, redisHistoryPrefix+city, 0, -1).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list history", "city", city, "err", err)
+		return []model.WeatherDetails{}
+	}
+	return decodeRedisHistory(members)
+}
+
+// ListAllHistory returns historical records grouped by city.
+func (r *RedisRepository) ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	cities, err := r.client.SMembers(ctx, redisHistoryCities).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list history cities", "err", err)
+		return map[string][]model.WeatherDetails{}
+	}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(cities))
+	for i, city := range cities {
+		cmds[i] = pipe.ZRange(ctx, redisHistoryPrefix+city, 0, -1)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis list all history", "err", err)
+		return map[string][]model.WeatherDetails{}
+	}
+	out := make(map[string][]model.WeatherDetails, len(cities))
+	for i, city := range cities {
+		out[city] = decodeRedisHistory(cmds[i].Val())
+	}
+	return out
+}
+
+// QueryHistory returns historical records matching q, grouped by city. The
+// time window is answered by a score range on each city's sorted set and
+// then checked exactly against UpdatedAt.
+func (r *RedisRepository) QueryHistory(ctx context.Context, q HistoryQuery) map[string][]model.WeatherDetails {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	out := make(map[string][]model.WeatherDetails)
+	cities, err := r.client.SMembers(ctx, redisHistoryCities).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list history cities", "err", err)
+		return out
+	}
+	rng := redisScoreRange(q)
+	pipe := r.client.Pipeline()
+	cmds := make(map[string]*redis.StringSliceCmd)
+	for _, city := range cities {
+		if q.matchesCity(city) {
+			cmds[city] = pipe.ZRangeByScore(ctx, redisHistoryPrefix+city, rng)
+		}
+	}
+	if len(cmds) == 0 {
+		return out
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis query history", "err", err)
+		return out
+	}
+	for city, cmd := range cmds {
+		var list []model.WeatherDetails
+		for _, data := range decodeRedisHistory(cmd.Val()) {
+			if q.matchesTime(data.UpdatedAt) {
+				list = append(list, data)
+			}
+		}
+		if len(list) > 0 {
+			out[city] = list
+		}
+	}
+	return out
+}
+
+// HistoryCities lists the cities with stored history.
+func (r *RedisRepository) HistoryCities(ctx context.Context) []string {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	cities, err := r.client.SMembers(ctx, redisHistoryCities).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list history cities", "err", err)
+	}
+	return cities
+}
+
+// CompactHistory rewrites the city's sorted set in a transaction that
+// watches it, so a concurrent append or compaction aborts the rewrite rather
+// than being lost. Only members that changed are removed or added. An
+// aborted rewrite is retried from a fresh read a few times.
+func (r *RedisRepository) CompactHistory(ctx context.Context, city string, compact CompactFunc) error {
+	return retryRedisTx(ctx, func() error {
+		return r.compactHistory(ctx, city, compact)
+	})
+}
+
+// retryRedisTx runs fn until it completes without a watched key changing
+// under it, at most redisCompactAttempts times.
+func retryRedisTx(ctx context.Context, fn func() error) error {
+	var err error
+	for attempt := 0; attempt < redisCompactAttempts; attempt++ {
+		if attempt > 0 {
+			// Back off by a random few milliseconds so that competing
+			// replicas do not collide again.
+			t := time.NewTimer(time.Duration(rand.Int63n(int64(attempt) * int64(20*time.Millisecond))))
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+		}
+		err = fn()
+		if !errors.Is(err, redis.TxFailedErr) {
+			return err
+		}
+	}
+	return err
+}
+
+func (r *RedisRepository) compactHistory(ctx context.Context, city string, compact CompactFunc) error {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	key := redisHistoryPrefix + city
+	return r.client.Watch(ctx, func(tx *redis.Tx) error {
+		members, err := tx.ZRange(ctx, key, 0, -1).Result()
+		if err != nil {
+			return err
+		}
+		out, changed := compact(decodeRedisHistory(members))
+		if !changed {
+			return nil
+		}
+		stale := make(map[string]bool, len(members))
+		for _, m := range members {
+			stale[m] = true
+		}
+		var add []redis.Z
+		for _, data := range out {
+			raw, err := json.Marshal(data)
+			if err != nil {
+				return err
+			}
+			if stale[string(raw)] {
+				delete(stale, string(raw))
+				continue
+			}
+			add = append(add, redis.Z{Score: float64(data.UpdatedAt.UnixNano()), Member: raw})
+		}
+		rem := make([]interface{}, 0, len(stale))
+		for m := range stale {
+			rem = append(rem, m)
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if len(out) == 0 {
+				pipe.Del(ctx, key)
+				pipe.SRem(ctx, redisHistoryCities, city)
+				return nil
+			}
+			if len(rem) > 0 {
+				pipe.ZRem(ctx, key, rem...)
+			}
+			if len(add) > 0 {
+				pipe.ZAdd(ctx, key, add...)
+			}
+			return nil
+		})
+		return err
+	}, key)
+}
+
+// AppendFloodResult adds an assessment to the city's flood sorted set,
+// scored by AssessedAt.
+func (r *RedisRepository) AppendFloodResult(ctx context.Context, data model.FloodAssessment) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		util.Log(ctx).Error("redis encode flood result", "err", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	key := floodKey(data.City)
+	pipe := r.client.TxPipeline()
+	pipe.ZAdd(ctx, redisFloodPrefix+key, redis.Z{Score: float64(data.AssessedAt.UnixNano()), Member: raw})
+	pipe.SAdd(ctx, redisFloodCities, key)
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis append flood result", "city", data.City, "err", err)
+	}
+}
+
+// QueryFloodResults returns flood assessments matching q, oldest first. With
+// a city only that city's sorted set is read.
+func (r *RedisRepository) QueryFloodResults(ctx context.Context, q HistoryQuery) []model.FloodAssessment {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	out := make([]model.FloodAssessment, 0)
+	keys := []string{floodKey(q.City)}
+	if q.City == "" {
+		var err error
+		if keys, err = r.client.SMembers(ctx, redisFloodCities).Result(); err != nil {
+			util.Log(ctx).Error("redis list flood cities", "err", err)
+			return out
+		}
+	}
+	rng := redisScoreRange(q)
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.StringSliceCmd, len(keys))
+	for i, key := range keys {
+		cmds[i] = pipe.ZRangeByScore(ctx, redisFloodPrefix+key, rng)
+	}
+	if len(cmds) == 0 {
+		return out
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis query flood results", "err", err)
+		return out
+	}
+	for _, cmd := range cmds {
+		for _, m := range cmd.Val() {
+			var data model.FloodAssessment
+			if err := json.Unmarshal([]byte(m), &data); err != nil {
+				continue
+			}
+			if q.matchesCity(data.City) && q.matchesTime(data.AssessedAt) {
+				out = append(out, data)
+			}
+		}
+	}
+	if len(cmds) > 1 {
+		sortFloodResults(out)
+	}
+	return out
+}
+
+// LatestFloodResult reads the highest-scored member of the city's set.
+func (r *RedisRepository) LatestFloodResult(ctx context.Context, city string) (model.FloodAssessment, bool) {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	members, err := r.client.ZRevRange(ctx, redisFloodPrefix+floodKey(city), 0, 0).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis latest flood result", "city", city, "err", err)
+		return model.FloodAssessment{}, false
+	}
+	if len(members) == 0 {
+		return model.FloodAssessment{}, false
+	}
+	var data model.FloodAssessment
+	if err := json.Unmarshal([]byte(members[0]), &data); err != nil {
+		util.Log(ctx).Error("redis decode flood result", "city", city, "err", err)
+		return model.FloodAssessment{}, false
+	}
+	return data, true
+}
+
+// TrimFloodResults trims each city's sorted set by score and rank. A city
+// whose set empties is removed from the city set in the same watched
+// transaction, so a concurrent append is never orphaned.
+func (r *RedisRepository) TrimFloodResults(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	keys, err := r.client.SMembers(ctx, redisFloodCities).Result()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, key := range keys {
+		if ctx.Err() != nil {
+			errs = append(errs, ctx.Err())
+			break
+		}
+		err := retryRedisTx(ctx, func() error {
+			n, err := r.trimFloodResults(ctx, key, cutoff, keep)
+			removed += n
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+func (r *RedisRepository) trimFloodResults(ctx context.Context, key string, cutoff time.Time, keep int) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	zkey := redisFloodPrefix + key
+	removed := 0
+	err := r.client.Watch(ctx, func(tx *redis.Tx) error {
+		total, err := tx.ZCard(ctx, zkey).Result()
+		if err != nil {
+			return err
+		}
+		var old int64
+		if !cutoff.IsZero() {
+			max := "(" + strconv.FormatInt(cutoff.UnixNano(), 10)
+			if old, err = tx.ZCount(ctx, zkey, "-inf", max).Result(); err != nil {
+				return err
+			}
+		}
+		drop := old
+		if keep > 0 && total-drop > int64(keep) {
+			drop = total - int64(keep)
+		}
+		if total > 0 && drop == 0 {
+			return nil
+		}
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			if drop == total {
+				pipe.Del(ctx, zkey)
+				pipe.SRem(ctx, redisFloodCities, key)
+				return nil
+			}
+			pipe.ZRemRangeByRank(ctx, zkey, 0, drop-1)
+			return nil
+		})
+		if err == nil {
+			removed = int(drop)
+		}
+		return err
+	}, zkey)
+	return removed, err
+}
+
+// Stats counts cache entries, history and flood results. Cache entries are
+// counted from the unexpired part of the cache index.
+func (r *RedisRepository) Stats(ctx context.Context) Stats {
+	ctx, cancel := context.WithTimeout(ctx, redisOpTimeout)
+	defer cancel()
+	var st Stats
+	live := liveCacheRange()
+	cached, err := r.client.ZCount(ctx, redisCacheIndex, live.Min, live.Max).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis count cache index", "err", err)
+	}
+	st.CachedEntries = int(cached)
+	cities, err := r.client.SMembers(ctx, redisHistoryCities).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list history cities", "err", err)
+		return st
+	}
+	floodKeys, err := r.client.SMembers(ctx, redisFloodCities).Result()
+	if err != nil {
+		util.Log(ctx).Error("redis list flood cities", "err", err)
+		return st
+	}
+	pipe := r.client.Pipeline()
+	cmds := make([]*redis.IntCmd, len(cities))
+	for i, city := range cities {
+		cmds[i] = pipe.ZCard(ctx, redisHistoryPrefix+city)
+	}
+	floodCmds := make([]*redis.IntCmd, len(floodKeys))
+	for i, key := range floodKeys {
+		floodCmds[i] = pipe.ZCard(ctx, redisFloodPrefix+key)
+	}
+	if _, err := pipe.Exec(ctx); err != nil {
+		util.Log(ctx).Error("redis stats", "err", err)
+		return st
+	}
+	st.HistoryCities = len(cities)
+	for _, cmd := range cmds {
+		st.HistorySnapshots += int(cmd.Val())
+	}
+	for _, cmd := range floodCmds {
+		st.FloodResults += int(cmd.Val())
+	}
+	return st
+}
+
+// redisScoreSlack widens score ranges past the float64 precision of a
+// UnixNano score, which is 256ns for current dates.
+const redisScoreSlack = int64(time.Microsecond)
+
+// redisScoreRange converts the time window of q into a sorted-set score
+// range. Scores are too coarse to tell the edges of [From, To) apart, so the
+// range is a little wider and callers filter the members with matchesTime.
+func redisScoreRange(q HistoryQuery) *redis.ZRangeBy {
+	rng := &redis.ZRangeBy{Min: "-inf", Max: "+inf"}
+	if !q.From.IsZero() {
+		rng.Min = strconv.FormatInt(q.From.UnixNano()-redisScoreSlack, 10)
+	}
+	if !q.To.IsZero() {
+		rng.Max = strconv.FormatInt(q.To.UnixNano()+redisScoreSlack, 10)
+	}
+	return rng
+}
+
+func decodeRedisHistory(members []string) []model.WeatherDetails {
+	out := make([]model.WeatherDetails, 0, len(members))
+	for _, m := range members {
+		var data model.WeatherDetails
+		if err := json.Unmarshal([]byte(m), &data); err != nil {
+			continue
+		}
+		out = append(out, data)
+	}
+	return out
+}
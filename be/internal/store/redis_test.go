@@ -0,0 +1,271 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/redis/go-redis/v9"
+)
+
+func newTestRedis(t *testing.T) (*RedisRepository, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	repo := NewRedisRepositoryWithClient(redis.NewClient(&redis.Options{Addr: mr.Addr()}))
+	t.Cleanup(repo.Close)
+	return repo, mr
+}
+
+func TestRedisCacheExpiry(t *testing.T) {
+	repo, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	repo.Set(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", Temperature: 31}, time.Minute)
+	repo.Set(ctx, "oslo", model.WeatherDetails{City: "Oslo", Temperature: 4}, time.Hour)
+
+	got, ok := repo.Get(ctx, "hanoi")
+	if !ok || got.Temperature != 31 {
+		t.Fatalf("Get(hanoi) = %+v, %v; want cached entry", got, ok)
+	}
+	if n := len(repo.List(ctx)); n != 2 {
+		t.Fatalf("List has %d entries, want 2", n)
+	}
+	if st := repo.Stats(ctx); st.CachedEntries != 2 {
+		t.Errorf("Stats.CachedEntries = %d, want 2", st.CachedEntries)
+	}
+
+	mr.FastForward(2 * time.Minute)
+	if _, ok := repo.Get(ctx, "hanoi"); ok {
+		t.Error("Get(hanoi) still hit after its TTL")
+	}
+	list := repo.List(ctx)
+	if _, ok := list["oslo"]; !ok || len(list) != 1 {
+		t.Errorf("List after expiry = %v, want only oslo", list)
+	}
+
+	// A record that is already past its expiry is not written at all.
+	repo.SetRecord(ctx, "paris", CacheRecord{ExpiresAt: time.Now().Add(-time.Second)})
+	if mr.Exists(redisCachePrefix + "paris") {
+		t.Error("expired record was stored")
+	}
+}
+
+func TestRedisCacheIndex(t *testing.T) {
+	repo, mr := newTestRedis(t)
+	ctx := context.Background()
+
+	// An index member whose expiry has passed is neither listed nor counted,
+	// even while its key lingers.
+	past := float64(time.Now().Add(-time.Minute).UnixMilli())
+	if _, err := mr.ZAdd(redisCacheIndex, past, "stale"); err != nil {
+		t.Fatal(err)
+	}
+	mr.Set(redisCachePrefix+"stale", `{"weather":{"city":"Stale"}}`)
+	repo.Set(ctx, "hanoi", model.WeatherDetails{City: "Hanoi"}, time.Hour)
+
+	if list := repo.List(ctx); len(list) != 1 || list["hanoi"].City != "Hanoi" {
+		t.Errorf("List = %v, want only hanoi", list)
+	}
+	if st := repo.Stats(ctx); st.CachedEntries != 1 {
+		t.Errorf("Stats.CachedEntries = %d, want 1", st.CachedEntries)
+	}
+	if members, _ := mr.ZMembers(redisCacheIndex); len(members) != 1 || members[0] != "hanoi" {
+		t.Errorf("cache index = %v after a write, want the stale member dropped", members)
+	}
+
+	// A key deleted behind the index's back is skipped by List.
+	mr.Del(redisCachePrefix + "hanoi")
+	if list := repo.List(ctx); len(list) != 0 {
+		t.Errorf("List = %v after the key was deleted, want none", list)
+	}
+}
+
+func TestRedisHistoryOrdering(t *testing.T) {
+	repo, _ := newTestRedis(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for _, offset := range []time.Duration{2 * time.Hour, 0, time.Hour} {
+		repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", UpdatedAt: base.Add(offset)})
+	}
+	repo.AppendHistory(ctx, "oslo", model.WeatherDetails{City: "Oslo", UpdatedAt: base})
+
+	list := repo.ListHistory(ctx, "hanoi")
+	if len(list) != 3 {
+		t.Fatalf("ListHistory returned %d snapshots, want 3", len(list))
+	}
+	for i, want := range []time.Duration{0, time.Hour, 2 * time.Hour} {
+		if !list[i].UpdatedAt.Equal(base.Add(want)) {
+			t.Errorf("snapshot %d at %v, want %v", i, list[i].UpdatedAt, base.Add(want))
+		}
+	}
+	all := repo.ListAllHistory(ctx)
+	if len(all["hanoi"]) != 3 || len(all["oslo"]) != 1 {
+		t.Errorf("ListAllHistory sizes = hanoi:%d oslo:%d, want 3 and 1", len(all["hanoi"]), len(all["oslo"]))
+	}
+	if got := repo.Stats(ctx); got.HistoryCities != 2 || got.HistorySnapshots != 4 {
+		t.Errorf("Stats = %+v, want 2 cities and 4 snapshots", got)
+	}
+}
+
+// The edges are closer together than a float64 UnixNano score can resolve,
+// so only the exact check after the score range keeps them apart.
+func TestRedisQueryHistoryBounds(t *testing.T) {
+	repo, _ := newTestRedis(t)
+	ctx := context.Background()
+	from := time.Date(2026, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	to := from.Add(time.Hour)
+
+	at := map[string]time.Time{
+		"before-from": from.Add(-100 * time.Nanosecond),
+		"from":        from,
+		"before-to":   to.Add(-100 * time.Nanosecond),
+		"to":          to,
+	}
+	for name, ts := range at {
+		repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", WindDir: name, UpdatedAt: ts})
+	}
+	repo.AppendHistory(ctx, "oslo", model.WeatherDetails{City: "Oslo", UpdatedAt: from})
+
+	got := repo.QueryHistory(ctx, HistoryQuery{City: "HANOI", From: from, To: to})
+	if len(got) != 1 {
+		t.Fatalf("QueryHistory returned cities %v, want only hanoi", got)
+	}
+	list := got["hanoi"]
+	if len(list) != 2 || list[0].WindDir != "from" || list[1].WindDir != "before-to" {
+		t.Errorf("QueryHistory = %+v, want the from and before-to snapshots", list)
+	}
+
+	if got := repo.QueryHistory(ctx, HistoryQuery{To: from}); len(got["hanoi"]) != 1 || len(got["oslo"]) != 0 {
+		t.Errorf("QueryHistory(To: from) = %+v, want only before-from", got)
+	}
+	if got := repo.QueryHistory(ctx, HistoryQuery{}); len(got["hanoi"]) != 4 || len(got["oslo"]) != 1 {
+		t.Errorf("unbounded QueryHistory = %+v, want everything", got)
+	}
+}
+
+func TestRedisFloodResults(t *testing.T) {
+	repo, mr := newTestRedis(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.2, AssessedAt: base.Add(time.Hour)})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.1, AssessedAt: base})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Oslo", Score: 0.3, AssessedAt: base.Add(30 * time.Minute)})
+	repo.AppendFloodResult(ctx, model.FloodAssessment{City: "Hanoi", Score: 0.4, AssessedAt: base.Add(2 * time.Hour)})
+
+	got := repo.QueryFloodResults(ctx, HistoryQuery{City: "hanoi", From: base, To: base.Add(2 * time.Hour)})
+	if len(got) != 2 || got[0].Score != 0.1 || got[1].Score != 0.2 {
+		t.Errorf("QueryFloodResults = %+v, want hanoi at 0.1 then 0.2", got)
+	}
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{}); len(got) != 4 || got[1].City != "Oslo" {
+		t.Errorf("unbounded QueryFloodResults = %+v, want all four oldest first", got)
+	}
+	if st := repo.Stats(ctx); st.FloodResults != 4 {
+		t.Errorf("Stats.FloodResults = %d, want 4", st.FloodResults)
+	}
+	if got, ok := repo.LatestFloodResult(ctx, "HANOI"); !ok || got.Score != 0.4 {
+		t.Errorf("LatestFloodResult(HANOI) = %+v, %v; want 0.4", got, ok)
+	}
+	if _, ok := repo.LatestFloodResult(ctx, "paris"); ok {
+		t.Error("LatestFloodResult(paris) found a result")
+	}
+
+	// Trim everything before the second Hanoi result, then keep only the
+	// newest one per city.
+	n, err := repo.TrimFloodResults(ctx, base.Add(time.Hour), 0)
+	if err != nil || n != 2 {
+		t.Fatalf("TrimFloodResults by age = %d, %v; want 2 removed", n, err)
+	}
+	if got := repo.QueryFloodResults(ctx, HistoryQuery{}); len(got) != 2 || got[0].Score != 0.2 || got[1].Score != 0.4 {
+		t.Errorf("results after the age trim = %+v, want hanoi at 0.2 and 0.4", got)
+	}
+	if n, err := repo.TrimFloodResults(ctx, time.Time{}, 1); err != nil || n != 1 {
+		t.Errorf("TrimFloodResults by count = %d, %v; want 1 removed", n, err)
+	}
+	if got, ok := repo.LatestFloodResult(ctx, "hanoi"); !ok || got.Score != 0.4 {
+		t.Errorf("LatestFloodResult after trimming = %+v, %v; want 0.4", got, ok)
+	}
+	if cities, _ := mr.Members(redisFloodCities); len(cities) != 1 || cities[0] != "hanoi" {
+		t.Errorf("flood cities = %v, want oslo removed once empty", cities)
+	}
+}
+
+func TestRedisMigrateFloodResults(t *testing.T) {
+	repo, mr := newTestRedis(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < redisMigrateBatch+3; i++ {
+		data := model.FloodAssessment{City: "Hanoi", Score: float64(i), AssessedAt: base.Add(time.Duration(i) * time.Minute)}
+		if i%2 == 1 {
+			data.City = "Oslo"
+		}
+		raw, _ := json.Marshal(data)
+		if _, err := mr.ZAdd(redisLegacyFloodResults, float64(data.AssessedAt.UnixNano()), string(raw)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := repo.migrateFloodResults(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if mr.Exists(redisLegacyFloodResults) {
+		t.Error("legacy flood set still exists")
+	}
+	if st := repo.Stats(ctx); st.FloodResults != redisMigrateBatch+3 {
+		t.Errorf("Stats.FloodResults = %d, want %d", st.FloodResults, redisMigrateBatch+3)
+	}
+	if got, ok := repo.LatestFloodResult(ctx, "oslo"); !ok || got.Score != redisMigrateBatch+1 {
+		t.Errorf("LatestFloodResult(oslo) = %+v, %v; want the last migrated", got, ok)
+	}
+}
+
+func TestRedisCompactHistoryRetriesAfterAppend(t *testing.T) {
+	repo, _ := newTestRedis(t)
+	ctx := context.Background()
+	base := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", Temperature: float64(i), UpdatedAt: base.Add(time.Duration(i) * time.Minute)})
+	}
+
+	calls := 0
+	err := repo.CompactHistory(ctx, "hanoi", func(list []model.WeatherDetails) ([]model.WeatherDetails, bool) {
+		calls++
+		if calls == 1 {
+			// An append between the read and the rewrite aborts it.
+			repo.AppendHistory(ctx, "hanoi", model.WeatherDetails{City: "Hanoi", Temperature: 9, UpdatedAt: base.Add(time.Hour)})
+		}
+		// Keep only the last snapshot of the first hour, plus anything later.
+		var out []model.WeatherDetails
+		for i, e := range list {
+			if e.UpdatedAt.Before(base.Add(time.Hour)) && i < 2 {
+				continue
+			}
+			out = append(out, e)
+		}
+		return out, true
+	})
+	if err != nil {
+		t.Fatalf("CompactHistory: %v", err)
+	}
+	if calls != 2 {
+		t.Errorf("compact ran %d times, want 2", calls)
+	}
+	got := repo.ListHistory(ctx, "hanoi")
+	if len(got) != 2 || got[0].Temperature != 2 || got[1].Temperature != 9 {
+		t.Errorf("history after compaction = %+v, want the last early snapshot and the concurrent one", got)
+	}
+
+	err = repo.CompactHistory(ctx, "hanoi", func([]model.WeatherDetails) ([]model.WeatherDetails, bool) {
+		return nil, true
+	})
+	if err != nil {
+		t.Fatalf("CompactHistory: %v", err)
+	}
+	if cities := repo.HistoryCities(ctx); len(cities) != 0 {
+		t.Errorf("HistoryCities = %v after emptying the only city, want none", cities)
+	}
+}
@@ -1,39 +1,136 @@
 package store
 
 import (
+	"context"
+	"sort"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/jeffhieun/weatherdatadashboard/internal/model"
 )
 
+// CacheRecord is a cached weather value. Past StaleAt (the soft TTL) the
+// value may still be served while it is refreshed; past ExpiresAt (the hard
+// TTL) it is gone. A zero StaleAt means the record is fresh until it expires.
 type CacheRecord struct {
 	Weather   model.WeatherDetails
+	StaleAt   time.Time
 	ExpiresAt time.Time
 }
 
+// Stale reports whether the record is past its soft TTL at now.
+func (r CacheRecord) Stale(now time.Time) bool {
+	return !r.StaleAt.IsZero() && now.After(r.StaleAt)
+}
+
+type ForecastRecord struct {
+	Forecast  model.Forecast
+	ExpiresAt time.Time
+}
+
+// HistoryQuery narrows a history lookup. Zero values leave that dimension
+// unbounded.
+type HistoryQuery struct {
+	City string    // matched case-insensitively
+	From time.Time // inclusive
+	To   time.Time // exclusive
+}
+
+func (q HistoryQuery) matchesCity(city string) bool {
+	return q.City == "" || strings.EqualFold(q.City, city)
+}
+
+// Stats are the repository's current sizes, cheap enough to read on every
+// metrics scrape.
+type Stats struct {
+	CachedEntries    int `json:"cachedEntries"`
+	HistoryCities    int `json:"historyCities"`
+	HistorySnapshots int `json:"historySnapshots"`
+	FloodResults     int `json:"floodResults"`
+}
+
+func (q HistoryQuery) matchesTime(t time.Time) bool {
+	return (q.From.IsZero() || !t.Before(q.From)) && (q.To.IsZero() || t.Before(q.To))
+}
+
+// CacheKey normalizes a city name so that "London", " london" and "LONDON"
+// share one cache entry. The weather service caches city lookups under it
+// and the metrics collector reads them back with it.
+func CacheKey(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
+
+// floodKey is the key a city's flood results are stored under, so that they
+// are found again whatever the case of the city name.
+func floodKey(city string) string {
+	return strings.ToLower(city)
+}
+
+// sortFloodResults orders results gathered from several cities oldest first.
+func sortFloodResults(list []model.FloodAssessment) {
+	sort.SliceStable(list, func(i, j int) bool { return list[i].AssessedAt.Before(list[j].AssessedAt) })
+}
+
+// CompactFunc rewrites one city's history, given oldest first, and reports
+// whether anything changed. Returning an empty list removes the city.
+type CompactFunc func(list []model.WeatherDetails) ([]model.WeatherDetails, bool)
+
 type WeatherRepository interface {
-	Get(city string) (model.WeatherDetails, bool)
-	Set(city string, data model.WeatherDetails, ttl time.Duration)
-	List() map[string]model.WeatherDetails
+	Get(ctx context.Context, city string) (model.WeatherDetails, bool)
+	Set(ctx context.Context, city string, data model.WeatherDetails, ttl time.Duration)
+	// GetRecord returns the record until its hard expiry, stale or not.
+	GetRecord(ctx context.Context, city string) (CacheRecord, bool)
+	// SetRecord stores rec as-is; it is evicted at rec.ExpiresAt.
+	SetRecord(ctx context.Context, city string, rec CacheRecord)
+	List(ctx context.Context) map[string]model.WeatherDetails
+	// Forecast cache APIs
+	GetForecast(ctx context.Context, key string) (model.Forecast, bool)
+	SetForecast(ctx context.Context, key string, data model.Forecast, ttl time.Duration)
 	// History APIs
-	AppendHistory(city string, data model.WeatherDetails)
-	ListHistory(city string) []model.WeatherDetails
-	ListAllHistory() map[string][]model.WeatherDetails
+	AppendHistory(ctx context.Context, city string, data model.WeatherDetails)
+	ListHistory(ctx context.Context, city string) []model.WeatherDetails
+	ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails
+	QueryHistory(ctx context.Context, q HistoryQuery) map[string][]model.WeatherDetails
+	// HistoryCities lists the cities with stored history.
+	HistoryCities(ctx context.Context) []string
+	// CompactHistory replaces the city's history with the result of
+	// compact, atomically with respect to AppendHistory.
+	CompactHistory(ctx context.Context, city string, compact CompactFunc) error
+	// Flood result APIs
+	AppendFloodResult(ctx context.Context, data model.FloodAssessment)
+	QueryFloodResults(ctx context.Context, q HistoryQuery) []model.FloodAssessment
+	// LatestFloodResult returns the city's most recent flood assessment.
+	LatestFloodResult(ctx context.Context, city string) (model.FloodAssessment, bool)
+	// TrimFloodResults deletes every city's assessments made before cutoff
+	// and all but its newest keep, and reports how many it deleted. A zero
+	// cutoff or keep skips that bound.
+	TrimFloodResults(ctx context.Context, cutoff time.Time, keep int) (int, error)
+	Stats(ctx context.Context) Stats
+	// Ping reports whether the backing store can serve requests.
+	Ping(ctx context.Context) error
 	Close()
 }
 
 type InMemoryRepository struct {
-	mu      sync.RWMutex
-	store   map[string]CacheRecord
-	history map[string][]model.WeatherDetails
+	mu        sync.RWMutex
+	store     map[string]CacheRecord
+	forecasts map[string]ForecastRecord
+	history   map[string][]model.WeatherDetails
+	// floods holds each city's assessments oldest first, by floodKey.
+	floods map[string][]model.FloodAssessment
 }
 
 func NewInMemoryRepository() *InMemoryRepository {
-	return &InMemoryRepository{store: make(map[string]CacheRecord), history: make(map[string][]model.WeatherDetails)}
+	return &InMemoryRepository{
+		store:     make(map[string]CacheRecord),
+		forecasts: make(map[string]ForecastRecord),
+		history:   make(map[string][]model.WeatherDetails),
+		floods:    make(map[string][]model.FloodAssessment),
+	}
 }
 
-func (r *InMemoryRepository) Get(city string) (model.WeatherDetails, bool) {
+func (r *InMemoryRepository) Get(ctx context.Context, city string) (model.WeatherDetails, bool) {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	rec, ok := r.store[city]
@@ -43,7 +140,7 @@ func (r *InMemoryRepository) Get(city string) (model.WeatherDetails, bool) {
 	return rec.Weather, true
 }
 
-func (r *InMemoryRepository) Set(city string, data model.WeatherDetails, ttl time.Duration) {
+func (r *InMemoryRepository) Set(ctx context.Context, city string, data model.WeatherDetails, ttl time.Duration) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.store[city] = CacheRecord{Weather: data, ExpiresAt: time.Now().Add(ttl)}
@@ -51,7 +148,23 @@ func (r *InMemoryRepository) Set(city string, data model.WeatherDetails, ttl tim
 	r.history[city] = append(r.history[city], data)
 }
 
-func (r *InMemoryRepository) List() map[string]model.WeatherDetails {
+func (r *InMemoryRepository) GetRecord(ctx context.Context, city string) (CacheRecord, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.store[city]
+	if !ok || time.Now().After(rec.ExpiresAt) {
+		return CacheRecord{}, false
+	}
+	return rec, true
+}
+
+func (r *InMemoryRepository) SetRecord(ctx context.Context, city string, rec CacheRecord) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.store[city] = rec
+}
+
+func (r *InMemoryRepository) List(ctx context.Context) map[string]model.WeatherDetails {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	result := make(map[string]model.WeatherDetails)
@@ -63,17 +176,35 @@ func (r *InMemoryRepository) List() map[string]model.WeatherDetails {
 	return result
 }
 
+func (r *InMemoryRepository) GetForecast(ctx context.Context, key string) (model.Forecast, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	rec, ok := r.forecasts[key]
+	if !ok || time.Now().After(rec.ExpiresAt) {
+		return model.Forecast{}, false
+	}
+	return rec.Forecast, true
+}
+
+func (r *InMemoryRepository) SetForecast(ctx context.Context, key string, data model.Forecast, ttl time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.forecasts[key] = ForecastRecord{Forecast: data, ExpiresAt: time.Now().Add(ttl)}
+}
+
+func (r *InMemoryRepository) Ping(ctx context.Context) error { return nil }
+
 func (r *InMemoryRepository) Close() {}
 
 // AppendHistory appends a snapshot without affecting the cache TTL/value.
-func (r *InMemoryRepository) AppendHistory(city string, data model.WeatherDetails) {
+func (r *InMemoryRepository) AppendHistory(ctx context.Context, city string, data model.WeatherDetails) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
 	r.history[city] = append(r.history[city], data)
 }
 
 // ListHistory returns all historical records for a city.
-func (r *InMemoryRepository) ListHistory(city string) []model.WeatherDetails {
+func (r *InMemoryRepository) ListHistory(ctx context.Context, city string) []model.WeatherDetails {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	list := r.history[city]
@@ -84,7 +215,7 @@ func (r *InMemoryRepository) ListHistory(city string) []model.WeatherDetails {
 }
 
 // ListAllHistory returns historical records grouped by city.
-func (r *InMemoryRepository) ListAllHistory() map[string][]model.WeatherDetails {
+func (r *InMemoryRepository) ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 	out := make(map[string][]model.WeatherDetails, len(r.history))
@@ -95,3 +226,134 @@ func (r *InMemoryRepository) ListAllHistory() map[string][]model.WeatherDetails
 	}
 	return out
 }
+
+// HistoryCities lists the cities with stored history.
+func (r *InMemoryRepository) HistoryCities(ctx context.Context) []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]string, 0, len(r.history))
+	for city := range r.history {
+		out = append(out, city)
+	}
+	return out
+}
+
+// CompactHistory runs compact on a copy of the city's history under the
+// write lock and stores the result.
+func (r *InMemoryRepository) CompactHistory(ctx context.Context, city string, compact CompactFunc) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	list := make([]model.WeatherDetails, len(r.history[city]))
+	copy(list, r.history[city])
+	out, changed := compact(list)
+	switch {
+	case !changed:
+	case len(out) == 0:
+		delete(r.history, city)
+	default:
+		r.history[city] = out
+	}
+	return nil
+}
+
+// QueryHistory returns historical records matching q, grouped by city.
+func (r *InMemoryRepository) QueryHistory(ctx context.Context, q HistoryQuery) map[string][]model.WeatherDetails {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string][]model.WeatherDetails)
+	for city, list := range r.history {
+		if !q.matchesCity(city) {
+			continue
+		}
+		for _, rec := range list {
+			if q.matchesTime(rec.UpdatedAt) {
+				out[city] = append(out[city], rec)
+			}
+		}
+	}
+	return out
+}
+
+// AppendFloodResult records a flood assessment, keeping the city's results
+// in AssessedAt order.
+func (r *InMemoryRepository) AppendFloodResult(ctx context.Context, data model.FloodAssessment) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := floodKey(data.City)
+	list := r.floods[key]
+	i := sort.Search(len(list), func(i int) bool { return list[i].AssessedAt.After(data.AssessedAt) })
+	list = append(list, model.FloodAssessment{})
+	copy(list[i+1:], list[i:])
+	list[i] = data
+	r.floods[key] = list
+}
+
+// QueryFloodResults returns flood assessments matching q, oldest first.
+func (r *InMemoryRepository) QueryFloodResults(ctx context.Context, q HistoryQuery) []model.FloodAssessment {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make([]model.FloodAssessment, 0)
+	for _, list := range r.floods {
+		for _, rec := range list {
+			if q.matchesCity(rec.City) && q.matchesTime(rec.AssessedAt) {
+				out = append(out, rec)
+			}
+		}
+	}
+	sortFloodResults(out)
+	return out
+}
+
+// LatestFloodResult returns the city's most recent flood assessment.
+func (r *InMemoryRepository) LatestFloodResult(ctx context.Context, city string) (model.FloodAssessment, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	list := r.floods[floodKey(city)]
+	if len(list) == 0 {
+		return model.FloodAssessment{}, false
+	}
+	return list[len(list)-1], true
+}
+
+// TrimFloodResults drops the oldest assessments of each city.
+func (r *InMemoryRepository) TrimFloodResults(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	removed := 0
+	for key, list := range r.floods {
+		drop := 0
+		if !cutoff.IsZero() {
+			drop = sort.Search(len(list), func(i int) bool { return !list[i].AssessedAt.Before(cutoff) })
+		}
+		if keep > 0 && len(list)-drop > keep {
+			drop = len(list) - keep
+		}
+		removed += drop
+		if drop == len(list) {
+			delete(r.floods, key)
+		} else if drop > 0 {
+			r.floods[key] = append([]model.FloodAssessment(nil), list[drop:]...)
+		}
+	}
+	return removed, nil
+}
+
+// Stats counts unexpired cache entries, history and flood results.
+func (r *InMemoryRepository) Stats(ctx context.Context) Stats {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	st := Stats{HistoryCities: len(r.history)}
+	now := time.Now()
+	for _, rec := range r.store {
+		if now.Before(rec.ExpiresAt) {
+			st.CachedEntries++
+		}
+	}
+	for _, list := range r.history {
+		st.HistorySnapshots += len(list)
+	}
+	for _, list := range r.floods {
+		st.FloodResults += len(list)
+	}
+	return st
+}
@@ -0,0 +1,178 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracedRepository opens a span around every call to the wrapped repository.
+type tracedRepository struct {
+	next store.WeatherRepository
+}
+
+// InstrumentRepository wraps repo so each operation gets a span named
+// "repository.<Method>". Lookups carry the key and whether it was a hit.
+func InstrumentRepository(repo store.WeatherRepository) store.WeatherRepository {
+	return tracedRepository{repo}
+}
+
+func startOp(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return Start(ctx, "repository."+op, attrs...)
+}
+
+func queryAttrs(q store.HistoryQuery) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{AttrCity.String(q.City)}
+	if !q.From.IsZero() {
+		attrs = append(attrs, attribute.String("query.from", q.From.Format(time.RFC3339)))
+	}
+	if !q.To.IsZero() {
+		attrs = append(attrs, attribute.String("query.to", q.To.Format(time.RFC3339)))
+	}
+	return attrs
+}
+
+func (r tracedRepository) Get(ctx context.Context, city string) (model.WeatherDetails, bool) {
+	ctx, span := startOp(ctx, "Get", AttrCacheKey.String(city))
+	defer span.End()
+	data, ok := r.next.Get(ctx, city)
+	span.SetAttributes(AttrCacheHit.Bool(ok))
+	return data, ok
+}
+
+func (r tracedRepository) Set(ctx context.Context, city string, data model.WeatherDetails, ttl time.Duration) {
+	ctx, span := startOp(ctx, "Set", AttrCacheKey.String(city))
+	defer span.End()
+	r.next.Set(ctx, city, data, ttl)
+}
+
+func (r tracedRepository) GetRecord(ctx context.Context, city string) (store.CacheRecord, bool) {
+	ctx, span := startOp(ctx, "GetRecord", AttrCacheKey.String(city))
+	defer span.End()
+	rec, ok := r.next.GetRecord(ctx, city)
+	span.SetAttributes(AttrCacheHit.Bool(ok))
+	if ok {
+		span.SetAttributes(attribute.Bool("cache.stale", rec.Stale(time.Now())))
+	}
+	return rec, ok
+}
+
+func (r tracedRepository) SetRecord(ctx context.Context, city string, rec store.CacheRecord) {
+	ctx, span := startOp(ctx, "SetRecord", AttrCacheKey.String(city))
+	defer span.End()
+	r.next.SetRecord(ctx, city, rec)
+}
+
+func (r tracedRepository) List(ctx context.Context) map[string]model.WeatherDetails {
+	ctx, span := startOp(ctx, "List")
+	defer span.End()
+	return r.next.List(ctx)
+}
+
+func (r tracedRepository) GetForecast(ctx context.Context, key string) (model.Forecast, bool) {
+	ctx, span := startOp(ctx, "GetForecast", AttrCacheKey.String(key))
+	defer span.End()
+	data, ok := r.next.GetForecast(ctx, key)
+	span.SetAttributes(AttrCacheHit.Bool(ok))
+	return data, ok
+}
+
+func (r tracedRepository) SetForecast(ctx context.Context, key string, data model.Forecast, ttl time.Duration) {
+	ctx, span := startOp(ctx, "SetForecast", AttrCacheKey.String(key))
+	defer span.End()
+	r.next.SetForecast(ctx, key, data, ttl)
+}
+
+func (r tracedRepository) AppendHistory(ctx context.Context, city string, data model.WeatherDetails) {
+	ctx, span := startOp(ctx, "AppendHistory", AttrCity.String(city))
+	defer span.End()
+	r.next.AppendHistory(ctx, city, data)
+}
+
+func (r tracedRepository) ListHistory(ctx context.Context, city string) []model.WeatherDetails {
+	ctx, span := startOp(ctx, "ListHistory", AttrCity.String(city))
+	defer span.End()
+	return r.next.ListHistory(ctx, city)
+}
+
+func (r tracedRepository) ListAllHistory(ctx context.Context) map[string][]model.WeatherDetails {
+	ctx, span := startOp(ctx, "ListAllHistory")
+	defer span.End()
+	return r.next.ListAllHistory(ctx)
+}
+
+func (r tracedRepository) QueryHistory(ctx context.Context, q store.HistoryQuery) map[string][]model.WeatherDetails {
+	ctx, span := startOp(ctx, "QueryHistory", queryAttrs(q)...)
+	defer span.End()
+	out := r.next.QueryHistory(ctx, q)
+	span.SetAttributes(attribute.Int("result.cities", len(out)))
+	return out
+}
+
+func (r tracedRepository) HistoryCities(ctx context.Context) []string {
+	ctx, span := startOp(ctx, "HistoryCities")
+	defer span.End()
+	return r.next.HistoryCities(ctx)
+}
+
+func (r tracedRepository) CompactHistory(ctx context.Context, city string, compact store.CompactFunc) error {
+	ctx, span := startOp(ctx, "CompactHistory", AttrCity.String(city))
+	err := r.next.CompactHistory(ctx, city, compact)
+	End(span, err)
+	return err
+}
+
+func (r tracedRepository) AppendFloodResult(ctx context.Context, data model.FloodAssessment) {
+	ctx, span := startOp(ctx, "AppendFloodResult", AttrCity.String(data.City))
+	defer span.End()
+	r.next.AppendFloodResult(ctx, data)
+}
+
+func (r tracedRepository) QueryFloodResults(ctx context.Context, q store.HistoryQuery) []model.FloodAssessment {
+	ctx, span := startOp(ctx, "QueryFloodResults", queryAttrs(q)...)
+	defer span.End()
+	out := r.next.QueryFloodResults(ctx, q)
+	span.SetAttributes(attribute.Int("result.count", len(out)))
+	return out
+}
+
+func (r tracedRepository) LatestFloodResult(ctx context.Context, city string) (model.FloodAssessment, bool) {
+	ctx, span := startOp(ctx, "LatestFloodResult", AttrCity.String(city))
+	defer span.End()
+	data, ok := r.next.LatestFloodResult(ctx, city)
+	span.SetAttributes(attribute.Bool("result.found", ok))
+	return data, ok
+}
+
+func (r tracedRepository) TrimFloodResults(ctx context.Context, cutoff time.Time, keep int) (int, error) {
+	attrs := []attribute.KeyValue{attribute.Int("trim.keep", keep)}
+	if !cutoff.IsZero() {
+		attrs = append(attrs, attribute.String("trim.cutoff", cutoff.Format(time.RFC3339)))
+	}
+	ctx, span := startOp(ctx, "TrimFloodResults", attrs...)
+	n, err := r.next.TrimFloodResults(ctx, cutoff, keep)
+	span.SetAttributes(attribute.Int("result.count", n))
+	End(span, err)
+	return n, err
+}
+
+func (r tracedRepository) Stats(ctx context.Context) store.Stats {
+	ctx, span := startOp(ctx, "Stats")
+	defer span.End()
+	return r.next.Stats(ctx)
+}
+
+func (r tracedRepository) Ping(ctx context.Context) error {
+	ctx, span := startOp(ctx, "Ping")
+	err := r.next.Ping(ctx)
+	End(span, err)
+	return err
+}
+
+func (r tracedRepository) Close() {
+	r.next.Close()
+}
@@ -0,0 +1,90 @@
+// Package tracing wires weatherd into OpenTelemetry. Spans are always
+// created through the global tracer provider, which is a no-op until Setup
+// or Install registers a real one, so tracing costs next to nothing when it
+// is not configured.
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "github.com/jeffhieun/weatherdatadashboard"
+
+// Attribute keys shared by the spans of the services and the repository.
+const (
+	AttrCity     = attribute.Key("weather.city")
+	AttrCacheHit = attribute.Key("cache.hit")
+	AttrCacheKey = attribute.Key("cache.key")
+)
+
+// Setup exports spans over OTLP/HTTP. The endpoint, headers and TLS
+// settings come from the standard OTEL_EXPORTER_OTLP_* variables, and
+// sampling from OTEL_TRACES_SAMPLER. The returned function flushes and
+// stops the exporter.
+func Setup(ctx context.Context, version string) (func(context.Context) error, error) {
+	exp, err := otlptracehttp.New(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create otlp exporter: %w", err)
+	}
+	tp, err := Install(ctx, exp, version)
+	if err != nil {
+		return nil, err
+	}
+	return tp.Shutdown, nil
+}
+
+// Install registers a tracer provider that batches spans to exp as the
+// global provider, along with W3C trace context propagation. Tests can pass
+// a tracetest.InMemoryExporter and call ForceFlush on the result before
+// reading the recorded spans.
+func Install(ctx context.Context, exp sdktrace.SpanExporter, version string) (*sdktrace.TracerProvider, error) {
+	// OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES override the defaults.
+	res, err := resource.New(ctx,
+		resource.WithAttributes(semconv.ServiceName("weatherd"), semconv.ServiceVersion(version)),
+		resource.WithFromEnv(),
+		resource.WithTelemetrySDK(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build trace resource: %w", err)
+	}
+	tp := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exp), sdktrace.WithResource(res))
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return tp, nil
+}
+
+// Start opens a span named name as a child of any span in ctx.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err, if any, on span and ends it.
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// Inject writes the trace context of ctx into outbound request headers.
+func Inject(ctx context.Context, header http.Header) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(header))
+}
+
+// Annotate adds attributes to the span in ctx, if any.
+func Annotate(ctx context.Context, attrs ...attribute.KeyValue) {
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+}
@@ -0,0 +1,112 @@
+package tracing_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jeffhieun/weatherdatadashboard/internal/model"
+	"github.com/jeffhieun/weatherdatadashboard/internal/service"
+	"github.com/jeffhieun/weatherdatadashboard/internal/store"
+	"github.com/jeffhieun/weatherdatadashboard/internal/tracing"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type fakeProvider struct{}
+
+func (fakeProvider) Geocode(ctx context.Context, city string) (model.City, error) {
+	return model.City{Name: "Hanoi", Lat: 21.03, Lon: 105.85}, nil
+}
+
+func (fakeProvider) Current(ctx context.Context, lat, lon float64) (model.WeatherDetails, error) {
+	return model.WeatherDetails{Temperature: 30, UpdatedAt: time.Now()}, nil
+}
+
+func (fakeProvider) Forecast(ctx context.Context, lat, lon float64, pastDays, days int) (model.Forecast, error) {
+	return model.Forecast{}, nil
+}
+
+func attrValue(attrs []attribute.KeyValue, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range attrs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestRequestSpans(t *testing.T) {
+	exp := tracetest.NewInMemoryExporter()
+	prev := otel.GetTracerProvider()
+	tp, err := tracing.Install(context.Background(), exp, "test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = tp.Shutdown(context.Background())
+		otel.SetTracerProvider(prev)
+	})
+
+	repo := tracing.InstrumentRepository(store.NewInMemoryRepository())
+	svc := service.NewDefaultWeatherService(repo, fakeProvider{}, time.Minute)
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(tracing.Middleware())
+	r.GET("/api/weather/details", func(c *gin.Context) {
+		data, err := svc.GetWeatherDetails(c.Request.Context(), c.Query("city"))
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		c.JSON(http.StatusOK, data)
+	})
+
+	for _, want := range []bool{false, true} {
+		exp.Reset()
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/weather/details?city=Hanoi", nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("status %d", w.Code)
+		}
+		if err := tp.ForceFlush(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+
+		spans := exp.GetSpans()
+		byName := make(map[string]tracetest.SpanStub)
+		for _, s := range spans {
+			byName[s.Name] = s
+		}
+		server, ok := byName["GET /api/weather/details"]
+		if !ok {
+			t.Fatalf("no server span among %d spans", len(spans))
+		}
+		details, ok := byName["weather.details"]
+		if !ok {
+			t.Fatal("no weather.details span")
+		}
+		lookup, ok := byName["repository.GetRecord"]
+		if !ok {
+			t.Fatal("no repository.GetRecord span")
+		}
+		if details.Parent.SpanID() != server.SpanContext.SpanID() {
+			t.Error("weather.details is not a child of the server span")
+		}
+		if lookup.Parent.SpanID() != details.SpanContext.SpanID() {
+			t.Error("repository.GetRecord is not a child of weather.details")
+		}
+		if v, _ := attrValue(details.Attributes, tracing.AttrCity); v.AsString() != "Hanoi" {
+			t.Errorf("weather.city = %q, want Hanoi", v.AsString())
+		}
+		for _, s := range []tracetest.SpanStub{details, lookup} {
+			if v, ok := attrValue(s.Attributes, tracing.AttrCacheHit); !ok || v.AsBool() != want {
+				t.Errorf("%s cache.hit = %v (set %v), want %v", s.Name, v.AsBool(), ok, want)
+			}
+		}
+	}
+}
@@ -1,7 +1,68 @@
 package util
 
-import "github.com/gin-gonic/gin"
+import (
+	"errors"
 
-func JSONError(c *gin.Context, code int, msg string) {
-	c.JSON(code, gin.H{"error": msg})
+	"github.com/gin-gonic/gin"
+)
+
+// Error kinds shared by services and handlers. Services return them (usually
+// wrapped in an *Error) and the API layer maps them to HTTP status codes
+// with errors.Is.
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrUpstreamUnavailable = errors.New("upstream unavailable")
+	ErrInvalidInput        = errors.New("invalid input")
+	ErrRateLimited         = errors.New("rate limited")
+)
+
+// Error is a typed error: Kind is one of the sentinels above, Message is safe
+// to show to clients, Details carries optional structured context and Err is
+// the underlying cause, which is logged but not returned to clients.
+type Error struct {
+	Kind    error
+	Message string
+	Details map[string]interface{}
+	Err     error
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+// Unwrap exposes both the kind and the cause to errors.Is and errors.As.
+func (e *Error) Unwrap() []error {
+	if e.Err != nil {
+		return []error{e.Kind, e.Err}
+	}
+	return []error{e.Kind}
+}
+
+func NotFound(msg string) *Error {
+	return &Error{Kind: ErrNotFound, Message: msg}
+}
+
+func InvalidInput(msg string, details map[string]interface{}) *Error {
+	return &Error{Kind: ErrInvalidInput, Message: msg, Details: details}
+}
+
+func UpstreamUnavailable(msg string, cause error) *Error {
+	return &Error{Kind: ErrUpstreamUnavailable, Message: msg, Err: cause}
+}
+
+func RateLimited(msg string, cause error) *Error {
+	return &Error{Kind: ErrRateLimited, Message: msg, Err: cause}
+}
+
+// JSONError writes the standard error envelope:
+// {"error": {"code": ..., "message": ..., "details": ...}}
+func JSONError(c *gin.Context, status int, code, msg string, details map[string]interface{}) {
+	body := gin.H{"code": code, "message": msg}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	c.AbortWithStatusJSON(status, gin.H{"error": body})
 }
@@ -1,8 +1,68 @@
 package util
 
 import (
-	"log"
+	"context"
+	"log/slog"
 	"os"
+	"strings"
 )
 
-var Logger = log.New(os.Stdout, "[weatherd] ", log.LstdFlags|log.Lshortfile)
+// Logger is the process-wide structured logger. Code that has a request
+// context should log through Log(ctx) so entries carry the request ID and
+// any other attributes attached along the way.
+var Logger = slog.New(slog.NewJSONHandler(os.Stdout, nil))
+
+// SetupLogger replaces Logger with one writing format ("json" or "text") at
+// level ("debug", "info", "warn" or "error") and makes it slog's default, so
+// the standard log package goes through it too.
+func SetupLogger(level, format string) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		lvl = slog.LevelInfo
+	}
+	opts := &slog.HandlerOptions{Level: lvl}
+	var h slog.Handler = slog.NewJSONHandler(os.Stdout, opts)
+	if strings.EqualFold(format, "text") {
+		h = slog.NewTextHandler(os.Stdout, opts)
+	}
+	Logger = slog.New(h)
+	slog.SetDefault(Logger)
+}
+
+type ctxKey int
+
+const (
+	loggerKey ctxKey = iota
+	requestIDKey
+)
+
+// WithLogger returns a copy of ctx carrying l.
+func WithLogger(ctx context.Context, l *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// WithLogAttrs returns a copy of ctx whose logger adds args to every entry.
+func WithLogAttrs(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, Log(ctx).With(args...))
+}
+
+// Log returns the logger carried by ctx, or Logger.
+func Log(ctx context.Context) *slog.Logger {
+	if l, ok := ctx.Value(loggerKey).(*slog.Logger); ok {
+		return l
+	}
+	return Logger
+}
+
+// WithRequestID returns a copy of ctx carrying id, with a logger that tags
+// every entry with it.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	ctx = context.WithValue(ctx, requestIDKey, id)
+	return WithLogAttrs(ctx, "request_id", id)
+}
+
+// RequestID returns the request ID carried by ctx, if any.
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
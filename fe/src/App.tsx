@@ -168,7 +168,7 @@ export default function App() {
       setLoading(false);
     }
     try {
-      const res = await axios.get(`/api/flood/risk?latitude=${city.lat}&longitude=${city.lon}`);
+      const res = await axios.get(`/api/flood/risk?latitude=${city.lat}&longitude=${city.lon}&city=${encodeURIComponent(city.name)}`);
       setFlood(res.data);
     } catch (err: any) {
       setFloodError(err?.message || "Failed to fetch flood risk");
@@ -1,5 +1,12 @@
 import axios from "axios";
 
+export interface FloodFactor {
+  name: string;
+  value: number;
+  unit: string;
+  contribution: number;
+}
+
 export interface FloodRiskData {
   flood_risk: "low" | "medium" | "high";
   probability: number;
@@ -7,17 +14,29 @@ export interface FloodRiskData {
     lat: number;
     lon: number;
   };
+  score?: number;
+  level?: "low" | "medium" | "high";
+  factors?: FloodFactor[];
+  method?: "precipitation" | "fallback";
+  assessed_at?: string;
 }
 
 export interface FloodResult {
   city: string;
+  lat?: number;
+  lon?: number;
   risk: "low" | "medium" | "high";
   probability: number;
+  method?: "precipitation" | "fallback";
   fetched_at: string;
 }
 
-export async function fetchFloodRisk(latitude: number, longitude: number): Promise<FloodRiskData> {
-  const res = await axios.get<FloodRiskData>(`/api/flood/risk?latitude=${latitude}&longitude=${longitude}`);
+export async function fetchFloodRisk(latitude: number, longitude: number, city?: string): Promise<FloodRiskData> {
+  let url = `/api/flood/risk?latitude=${latitude}&longitude=${longitude}`;
+  if (city) {
+    url += `&city=${encodeURIComponent(city)}`;
+  }
+  const res = await axios.get<FloodRiskData>(url);
   return res.data;
 }
 
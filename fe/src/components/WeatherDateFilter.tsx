@@ -40,7 +40,7 @@ export default function WeatherDateFilter() {
     // Automatically fetch flood risk data for the selected city
     setLoadingFloodRisk(true);
     try {
-      const risk = await fetchFloodRisk(city.lat, city.lon);
+      const risk = await fetchFloodRisk(city.lat, city.lon, city.name);
       setFloodRisk(risk);
     } catch (err) {
       console.error("Failed to fetch flood risk:", err);